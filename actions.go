@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action is an operation on the highlighted challenge in interactive mode
+type Action string
+
+const (
+	ActionSelect   Action = "select"    // print name, tags and path, then exit
+	ActionEdit     Action = "edit"      // open challenge.yml in $EDITOR, then exit
+	ActionCd       Action = "cd"        // print the challenge directory, then exit
+	ActionCopyPath Action = "copy-path" // copy the challenge.yml path to the clipboard
+	ActionCopyName Action = "copy-name" // copy the challenge name to the clipboard
+	ActionFiles    Action = "files"     // print the files under public/, then exit
+)
+
+// actions lists the actions in the order they are documented and shown in the help line
+var actions = []Action{ActionSelect, ActionEdit, ActionCd, ActionCopyPath, ActionCopyName, ActionFiles}
+
+// defaultKeys binds every action to a key that is not used for editing or navigation
+var defaultKeys = map[Action]string{
+	ActionSelect:   "enter",
+	ActionEdit:     "ctrl-o",
+	ActionCd:       "ctrl-g",
+	ActionCopyPath: "ctrl-y",
+	ActionCopyName: "alt-y",
+	ActionFiles:    "ctrl-x",
+}
+
+// keyBindings maps key presses to actions
+type keyBindings map[key]Action
+
+// newKeyBindings builds the key bindings from the defaults and the `keys:`
+// section of config.yaml, which maps action names to keys. Binding an action
+// to an empty string unbinds it.
+func newKeyBindings(overrides map[string]string) (keyBindings, error) {
+	for name := range overrides {
+		if _, ok := defaultKeys[Action(name)]; !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+	}
+
+	bindings := make(keyBindings)
+	for _, action := range actions {
+		spec := defaultKeys[action]
+		if override, ok := overrides[string(action)]; ok {
+			spec = override
+		}
+		if spec == "" {
+			continue
+		}
+
+		k, err := parseKeySpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key for %s: %w", action, err)
+		}
+		if other, ok := bindings[k]; ok {
+			return nil, fmt.Errorf("key %q is bound to both %s and %s", spec, other, action)
+		}
+		bindings[k] = action
+	}
+	return bindings, nil
+}
+
+// ctrlKeyAliases are the Ctrl combinations that terminals send as the same
+// byte as another key, so they would never reach the binding
+var ctrlKeyAliases = map[byte]string{
+	'h': "Backspace",
+	'i': "Tab",
+	'j': "Enter",
+	'm': "Enter",
+}
+
+// builtinKeys are the keys the interactive UI handles itself, with what they
+// do. Binding an action to one of them would silently take it over.
+var builtinKeys = map[key]string{
+	{kind: keyTab}:          "marking challenges",
+	{kind: keyCtrl, r: 'c'}: "quitting",
+	{kind: keyCtrl, r: 't'}: "toggling fuzzy matching",
+	{kind: keyCtrl, r: 'p'}: "toggling the preview",
+	{kind: keyCtrl, r: 'a'}: "moving the cursor",
+	{kind: keyCtrl, r: 'e'}: "moving the cursor",
+	{kind: keyCtrl, r: 'b'}: "moving the cursor",
+	{kind: keyCtrl, r: 'f'}: "moving the cursor",
+	{kind: keyCtrl, r: 'd'}: "deleting text",
+	{kind: keyCtrl, r: 'w'}: "deleting text",
+	{kind: keyCtrl, r: 'u'}: "deleting text",
+	{kind: keyCtrl, r: 'k'}: "deleting text",
+	{kind: keyCtrl, r: 'l'}: "redrawing the screen",
+	{kind: keyAlt, r: 'b'}:  "moving the cursor",
+	{kind: keyAlt, r: 'f'}:  "moving the cursor",
+	{kind: keyAlt, r: '<'}:  "selecting the first row",
+	{kind: keyAlt, r: '>'}:  "selecting the last row",
+}
+
+// parseKeySpec parses a key name such as "enter", "ctrl-o" or "alt-y". Keys
+// in builtinKeys are rejected, and Ctrl+H, Ctrl+I, Ctrl+J and Ctrl+M cannot
+// be told apart from Backspace, Tab and Enter.
+func parseKeySpec(spec string) (key, error) {
+	k, err := parseKeyName(spec)
+	if err != nil {
+		return key{}, err
+	}
+	if use, ok := builtinKeys[k]; ok {
+		return key{}, fmt.Errorf("%q is reserved for %s", spec, use)
+	}
+	return k, nil
+}
+
+// parseKeyName converts a key name into the key the reader reports for it
+func parseKeyName(spec string) (key, error) {
+	s := strings.ToLower(strings.TrimSpace(spec))
+	switch s {
+	case "enter":
+		return key{kind: keyEnter}, nil
+	case "tab":
+		return key{kind: keyTab}, nil
+	}
+
+	if letter, ok := strings.CutPrefix(s, "ctrl-"); ok {
+		if len(letter) != 1 || letter[0] < 'a' || letter[0] > 'z' {
+			return key{}, fmt.Errorf("%q: expected ctrl- followed by a letter", spec)
+		}
+		if name, ok := ctrlKeyAliases[letter[0]]; ok {
+			return key{}, fmt.Errorf("%q cannot be bound: terminals send it as %s", spec, name)
+		}
+		return key{kind: keyCtrl, r: rune(letter[0])}, nil
+	}
+	if char, ok := strings.CutPrefix(s, "alt-"); ok {
+		if utf8.RuneCountInString(char) != 1 {
+			return key{}, fmt.Errorf("%q: expected alt- followed by a single character", spec)
+		}
+		r, _ := utf8.DecodeRuneInString(char)
+		return key{kind: keyAlt, r: r}, nil
+	}
+	return key{}, fmt.Errorf("%q: expected enter, ctrl-<letter> or alt-<char>", spec)
+}
+
+// keyLabel returns the name of a key as shown in the help line, e.g. "Ctrl+O"
+func keyLabel(k key) string {
+	switch k.kind {
+	case keyEnter:
+		return "Enter"
+	case keyTab:
+		return "Tab"
+	case keyCtrl:
+		return "Ctrl+" + strings.ToUpper(string(k.r))
+	case keyAlt:
+		return "Alt+" + strings.ToUpper(string(k.r))
+	}
+	return "?"
+}
+
+// help lists the bound actions for the help line, e.g. "Enter: select  Ctrl+O: edit"
+func (b keyBindings) help() string {
+	var parts []string
+	for _, action := range actions {
+		for k, bound := range b {
+			if bound == action {
+				parts = append(parts, keyLabel(k)+": "+string(action))
+			}
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// exits reports whether the action ends the interactive session. Copying to
+// the clipboard keeps the UI open so that several values can be copied.
+func (a Action) exits() bool {
+	return a != ActionCopyPath && a != ActionCopyName
+}
+
+// runAction performs an action that ends the interactive session. Results are
+// written to out (stdout) so that they can be captured by a shell wrapper,
+// while the editor is attached to the terminal tty.
+func runAction(action Action, result ChallengeResult, out io.Writer, tty *os.File) error {
+	switch action {
+	case ActionSelect:
+		fmt.Fprintf(out, "Selected: %s\n", result.Name)
+		fmt.Fprintf(out, "Tags: %s\n", strings.Join(result.Tags, ", "))
+		fmt.Fprintf(out, "Path: %s\n", result.FilePath)
+	case ActionEdit:
+		return openInEditor(result, tty)
+	case ActionCd:
+		dir, err := filepath.Abs(filepath.Dir(result.FilePath))
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s is not checked out (found on branch %s)\n", dir, result.BranchName)
+		}
+		fmt.Fprintln(out, dir)
+	case ActionFiles:
+		preview := loadChallengePreview(result)
+		if preview.err != nil {
+			return preview.err
+		}
+		dir := filepath.Dir(result.FilePath)
+		for _, file := range preview.publicFiles {
+			fmt.Fprintln(out, filepath.Join(dir, filepath.FromSlash(file)))
+		}
+	default:
+		return fmt.Errorf("action %s cannot be run on exit", action)
+	}
+	return nil
+}
+
+// openInEditor opens the challenge.yml of a result in $VISUAL or $EDITOR
+// (falling back to vi). A challenge that only exists on another branch is
+// written to a temporary file first; the copy is kept so that edits are not lost.
+func openInEditor(result ChallengeResult, tty *os.File) error {
+	path, err := editablePath(result)
+	if err != nil {
+		return err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = tty
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %q: %w", editor, err)
+	}
+
+	if path != result.FilePath {
+		fmt.Fprintf(os.Stderr, "Opened a copy of %s from branch %s: %s\n", result.FilePath, result.BranchName, path)
+	}
+	return nil
+}
+
+// editablePath returns a path to the challenge.yml of a result on disk. The
+// working tree file is used unless the result comes from a branch other than
+// the one checked out, in which case the branch's version is copied to a
+// temporary file.
+func editablePath(result ChallengeResult) (string, error) {
+	if result.Ref == "" {
+		return result.FilePath, nil
+	}
+	if current, err := getCurrentBranch(); err == nil && current != "" && (result.Ref == current || result.Ref == "refs/heads/"+current) {
+		if _, err := os.Stat(result.FilePath); err == nil {
+			return result.FilePath, nil
+		}
+	}
+
+	content, err := getFileContentFromBranch(result.Ref, result.FilePath)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.ReplaceAll(result.BranchName, "/", "-")
+	file, err := os.CreateTemp("", "searchall-"+name+"-*-challenge.yml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(content); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// osc52 returns the OSC 52 escape sequence that asks the terminal to put text
+// on the system clipboard. This works over SSH, unlike calling a clipboard tool.
+func osc52(text string) string {
+	return "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
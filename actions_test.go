@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeySpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    key
+		wantErr bool
+	}{
+		{"enter", key{kind: keyEnter}, false},
+		{"Tab", key{}, true},
+		{"ctrl-t", key{}, true},
+		{"alt-b", key{}, true},
+		{"ctrl-o", key{kind: keyCtrl, r: 'o'}, false},
+		{"Ctrl-G", key{kind: keyCtrl, r: 'g'}, false},
+		{"alt-y", key{kind: keyAlt, r: 'y'}, false},
+		{"alt-.", key{kind: keyAlt, r: '.'}, false},
+		{"alt->", key{}, true},
+		{"ctrl-c", key{}, true},
+		{"ctrl-1", key{}, true},
+		{"ctrl-ab", key{}, true},
+		{"ctrl-m", key{}, true},
+		{"ctrl-i", key{}, true},
+		{"ctrl-h", key{}, true},
+		{"ctrl-j", key{}, true},
+		{"alt-", key{}, true},
+		{"x", key{}, true},
+		{"f1", key{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseKeySpec(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseKeySpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseKeySpec(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeySpecBuiltinKeys(t *testing.T) {
+	// Tab marks challenges, so binding it would silently disable that
+	_, err := parseKeySpec("tab")
+	if err == nil || !strings.Contains(err.Error(), "marking challenges") {
+		t.Errorf("Expected tab to be reserved for marking, got %v", err)
+	}
+
+	// Every built-in key is rejected, and only those
+	for k := range builtinKeys {
+		var spec string
+		switch k.kind {
+		case keyTab:
+			spec = "tab"
+		case keyCtrl:
+			spec = "ctrl-" + string(k.r)
+		case keyAlt:
+			spec = "alt-" + string(k.r)
+		}
+		if _, err := parseKeySpec(spec); err == nil {
+			t.Errorf("Expected %s to be reserved", spec)
+		}
+	}
+	for _, spec := range defaultKeys {
+		if _, err := parseKeySpec(spec); err != nil {
+			t.Errorf("Expected the default key %s to be accepted, got %v", spec, err)
+		}
+	}
+}
+
+func TestNewKeyBindings(t *testing.T) {
+	bindings, err := newKeyBindings(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error for defaults: %v", err)
+	}
+	if len(bindings) != len(actions) {
+		t.Errorf("Expected every action to be bound by default, got %v", bindings)
+	}
+	if bindings[key{kind: keyEnter}] != ActionSelect {
+		t.Errorf("Expected Enter to select, got %q", bindings[key{kind: keyEnter}])
+	}
+
+	// Overrides replace the default key, and an empty key unbinds the action
+	bindings, err = newKeyBindings(map[string]string{"edit": "alt-e", "files": ""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bindings[key{kind: keyAlt, r: 'e'}] != ActionEdit {
+		t.Error("Expected Alt+E to open the editor")
+	}
+	if _, ok := bindings[key{kind: keyCtrl, r: 'o'}]; ok {
+		t.Error("Expected Ctrl+O to be unbound after rebinding edit")
+	}
+	for _, action := range bindings {
+		if action == ActionFiles {
+			t.Error("Expected files to be unbound")
+		}
+	}
+
+	errorCases := []map[string]string{
+		{"launch": "ctrl-l"},              // unknown action
+		{"edit": "ctrl-g"},                // same key as cd
+		{"select": "ctrl-c"},              // reserved key
+		{"copy-name": "not a key at all"}, // unparsable key
+	}
+	for _, overrides := range errorCases {
+		if _, err := newKeyBindings(overrides); err == nil {
+			t.Errorf("Expected error for %v", overrides)
+		}
+	}
+}
+
+func TestKeyBindingsHelp(t *testing.T) {
+	bindings, err := newKeyBindings(map[string]string{"cd": "", "copy-path": "", "copy-name": "", "files": ""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got, want := bindings.help(), "Enter: select  Ctrl+O: edit"; got != want {
+		t.Errorf("help() = %q, want %q", got, want)
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	got := osc52("web/chall_1/challenge.yml")
+	want := "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte("web/chall_1/challenge.yml")) + "\a"
+	if got != want {
+		t.Errorf("osc52() = %q, want %q", got, want)
+	}
+}
+
+func TestRunActionOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	challDir := filepath.Join(tmpDir, "web", "chall_1")
+	if err := os.MkdirAll(filepath.Join(challDir, "public"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	challengePath := filepath.Join(challDir, "challenge.yml")
+	if err := os.WriteFile(challengePath, []byte("name: \"Action Test\"\ntags:\n  - web\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test challenge: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(challDir, "public", "handout.zip"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create public file: %v", err)
+	}
+	result := ChallengeResult{Name: "Action Test", Tags: []string{"web", "easy"}, FilePath: challengePath}
+
+	tests := []struct {
+		action Action
+		want   string
+	}{
+		{ActionSelect, "Selected: Action Test\nTags: web, easy\nPath: " + challengePath + "\n"},
+		{ActionCd, challDir + "\n"},
+		{ActionFiles, filepath.Join(challDir, "public", "handout.zip") + "\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := runAction(tt.action, result, &out, nil); err != nil {
+			t.Errorf("runAction(%s) unexpected error: %v", tt.action, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("runAction(%s) wrote %q, want %q", tt.action, out.String(), tt.want)
+		}
+	}
+
+	if err := runAction(ActionCopyName, result, &bytes.Buffer{}, nil); err == nil {
+		t.Error("Expected error for an action that does not exit")
+	}
+}
+
+func TestEditablePath(t *testing.T) {
+	currentBranch, err := getCurrentBranch()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+	filePath := "osint/chall_1/challenge.yml"
+
+	// The checked out branch is edited in place
+	path, err := editablePath(ChallengeResult{FilePath: filePath, BranchName: currentBranch, Ref: "refs/heads/" + currentBranch})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != filePath {
+		t.Errorf("Expected working tree path %q, got %q", filePath, path)
+	}
+
+	// Any other revision is copied to a temporary file
+	path, err = editablePath(ChallengeResult{FilePath: filePath, BranchName: "HEAD", Ref: "HEAD"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.Remove(path)
+	if path == filePath || !strings.HasSuffix(path, "-challenge.yml") {
+		t.Errorf("Expected a temporary copy, got %q", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read temporary copy: %v", err)
+	}
+	if !strings.Contains(string(content), "Social Media Investigation") {
+		t.Errorf("Expected the branch content in the copy, got %q", content)
+	}
+}
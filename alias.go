@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// newAliasTable builds the lookup used by the query parser from the
+// `aliases:` section of config.yaml. Each entry is a group of synonyms: the
+// key and every tag listed under it. The table maps each lower-cased member
+// to all members of its groups, so a search for any of them finds the others.
+func newAliasTable(aliases map[string][]string) map[string][]string {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	// Iterate in key order so that the expansion is deterministic
+	keys := make([]string, 0, len(aliases))
+	for key := range aliases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	table := make(map[string][]string)
+	for _, key := range keys {
+		group := append([]string{key}, aliases[key]...)
+		for _, member := range group {
+			lower := strings.ToLower(member)
+			for _, synonym := range group {
+				if !containsFold(table[lower], synonym) {
+					table[lower] = append(table[lower], synonym)
+				}
+			}
+		}
+	}
+	return table
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// AliasUsage reports an alias group whose members are used as tags by
+// different challenges, e.g. some tagged "geo" and others "geolocation"
+type AliasUsage struct {
+	Alias string         `json:"alias"` // Key of the group in config.yaml
+	Tags  map[string]int `json:"tags"`  // Number of challenges using each member
+}
+
+// findInconsistentAliases returns the alias groups of which more than one
+// member is in use, sorted by alias
+func findInconsistentAliases(stats []TagStat, aliases map[string][]string) []AliasUsage {
+	var usages []AliasUsage
+	for alias, members := range aliases {
+		group := append([]string{alias}, members...)
+		used := make(map[string]int)
+		for _, stat := range stats {
+			if containsFold(group, stat.Tag) {
+				used[stat.Tag] += stat.Count
+			}
+		}
+		if len(used) > 1 {
+			usages = append(usages, AliasUsage{Alias: alias, Tags: used})
+		}
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Alias < usages[j].Alias
+	})
+	return usages
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewAliasTable(t *testing.T) {
+	table := newAliasTable(map[string][]string{
+		"geo":      {"geolocation", "GEOINT"},
+		"location": {"geolocation"},
+		"sqli":     {"sql-injection"},
+	})
+
+	tests := []struct {
+		term     string
+		expected []string
+	}{
+		{"sqli", []string{"sqli", "sql-injection"}},
+		{"sql-injection", []string{"sqli", "sql-injection"}},
+		{"geoint", []string{"geo", "geolocation", "GEOINT"}},
+		// A tag in two groups expands to the members of both
+		{"geolocation", []string{"geo", "geolocation", "GEOINT", "location"}},
+		{"web", nil},
+	}
+
+	for _, tt := range tests {
+		if got := table[tt.term]; !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("table[%q] = %v, want %v", tt.term, got, tt.expected)
+		}
+	}
+
+	if newAliasTable(nil) != nil {
+		t.Error("Expected nil table without aliases")
+	}
+}
+
+func TestFindInconsistentAliases(t *testing.T) {
+	stats := []TagStat{
+		{Tag: "geolocation", Count: 3},
+		{Tag: "Geo", Count: 1},
+		{Tag: "sql-injection", Count: 2},
+		{Tag: "web", Count: 4},
+	}
+	aliases := map[string][]string{
+		"geo":  {"geolocation", "geoint"},
+		"sqli": {"sql-injection"},
+	}
+
+	got := findInconsistentAliases(stats, aliases)
+	expected := []AliasUsage{{Alias: "geo", Tags: map[string]int{"geolocation": 3, "Geo": 1}}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
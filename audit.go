@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// auditSchemaVersion is the version of the JSON written by `searchall audit --format json`
+const auditSchemaVersion = 1
+
+// LeakSeverity grades how serious a flag leak is
+type LeakSeverity int
+
+const (
+	LeakMedium   LeakSeverity = iota // Something that looks like a flag but is not a known one
+	LeakHigh                         // The flag of another challenge, or an encoded copy of the flag
+	LeakCritical                     // The challenge's own flag in plain text
+)
+
+// leakSeverityNames are the names used in output and in --fail-on
+var leakSeverityNames = map[LeakSeverity]string{
+	LeakMedium:   "medium",
+	LeakHigh:     "high",
+	LeakCritical: "critical",
+}
+
+// String returns the name of the severity
+func (s LeakSeverity) String() string {
+	return leakSeverityNames[s]
+}
+
+// MarshalJSON writes the severity by name
+func (s LeakSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// parseLeakSeverity converts a severity name into a LeakSeverity
+func parseLeakSeverity(name string) (LeakSeverity, error) {
+	for severity, severityName := range leakSeverityNames {
+		if strings.EqualFold(name, severityName) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (expected critical, high or medium)", name)
+}
+
+// AuditConfig is the `audit:` section of config.yaml
+type AuditConfig struct {
+	FlagPatterns []string `yaml:"flag_patterns"` // Extra regular expressions for flag-like strings
+	Allow        []string `yaml:"allow"`         // Strings that may appear in public files, e.g. decoy flags
+}
+
+// LeakFinding is a flag, or something like one, found in a public file
+type LeakFinding struct {
+	Severity  LeakSeverity `json:"severity"`
+	Branch    string       `json:"branch"` // Empty for the working tree
+	Path      string       `json:"path"`
+	Line      int          `json:"line"` // 0 for binary files
+	Challenge string       `json:"challenge"`
+	Match     string       `json:"match"` // Shortened by redactFlag
+	Message   string       `json:"message"`
+}
+
+// String formats the finding as "severity: [branch] path:line: message"
+func (f LeakFinding) String() string {
+	location := f.Path
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	if f.Branch != "" {
+		location = fmt.Sprintf("[%s] %s", f.Branch, location)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, location, f.Message)
+}
+
+// auditTarget is a challenge together with the public files distributed with it
+type auditTarget struct {
+	Challenge ChallengeResult
+	Files     []string                          // Paths of the public files
+	Read      func(path string) ([]byte, error) // Reads a public file from the same source
+}
+
+// leakScanner looks for flags in file contents
+type leakScanner struct {
+	flags    map[string]string // Known flag to the name of its challenge
+	patterns []*regexp.Regexp  // Flag-like strings
+	allow    []string
+}
+
+// defaultFlagPattern matches strings in the usual flag{...} format
+const defaultFlagPattern = `(?i)flag\{[^{}\s]{1,200}\}`
+
+// newLeakScanner builds a scanner that knows the flags of targets. Besides
+// the flag{...} format, a pattern is added for the prefix of every known
+// flag, e.g. ctf{...} when a challenge uses that format.
+func newLeakScanner(targets []auditTarget, config AuditConfig) (*leakScanner, error) {
+	scanner := &leakScanner{flags: make(map[string]string), allow: config.Allow}
+
+	sources := []string{defaultFlagPattern}
+	prefixes := make(map[string]bool)
+	for _, target := range targets {
+		value := strings.TrimSpace(target.Challenge.Flag)
+		if value == "" {
+			continue
+		}
+		scanner.flags[value] = target.Challenge.Name
+		if i := strings.Index(value, "{"); i > 0 && strings.HasSuffix(value, "}") {
+			prefix := strings.ToLower(value[:i])
+			if prefix != "flag" && !prefixes[prefix] {
+				prefixes[prefix] = true
+				sources = append(sources, `(?i)`+regexp.QuoteMeta(prefix)+`\{[^{}\s]{1,200}\}`)
+			}
+		}
+	}
+	sources = append(sources, config.FlagPatterns...)
+
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flag pattern %q: %w", source, err)
+		}
+		scanner.patterns = append(scanner.patterns, pattern)
+	}
+	return scanner, nil
+}
+
+// redactFlag shortens a matched flag so that reports, which end up in CI logs
+// and issues, do not leak it again: the text up to the opening brace is kept,
+// followed by at most three characters of the secret and "…"
+func redactFlag(match string) string {
+	prefix, secret := "", match
+	if i := strings.Index(match, "{"); i >= 0 {
+		prefix, secret = match[:i+1], strings.TrimSuffix(match[i+1:], "}")
+	}
+	runes := []rune(secret)
+	return prefix + string(runes[:min(3, len(runes)/2)]) + "…"
+}
+
+// scan returns the leaks in content of a file belonging to challenge. Each
+// distinct string is reported once, at its first occurrence, with the match
+// redacted.
+func (s *leakScanner) scan(content []byte, challenge ChallengeResult) []LeakFinding {
+	var findings []LeakFinding
+	reported := make(map[string]bool)
+	report := func(offset int, match string, severity LeakSeverity, message string) {
+		if reported[match] || containsFold(s.allow, match) {
+			return
+		}
+		reported[match] = true
+		findings = append(findings, LeakFinding{Severity: severity, Line: lineAt(content, offset), Match: redactFlag(match), Message: message})
+	}
+
+	// Known flags, the challenge's own first so it wins over its encodings
+	ownFlag := strings.TrimSpace(challenge.Flag)
+	known := make([]string, 0, len(s.flags))
+	for value := range s.flags {
+		known = append(known, value)
+	}
+	sort.Slice(known, func(i, j int) bool {
+		if (known[i] == ownFlag) != (known[j] == ownFlag) {
+			return known[i] == ownFlag
+		}
+		return known[i] < known[j]
+	})
+
+	for _, value := range known {
+		if i := bytes.Index(content, []byte(value)); i >= 0 {
+			if value == ownFlag {
+				report(i, value, LeakCritical, "contains the flag of this challenge")
+			} else {
+				report(i, value, LeakHigh, fmt.Sprintf("contains the flag of %q", s.flags[value]))
+			}
+		}
+		if value != ownFlag {
+			continue
+		}
+		for _, encoded := range encodeFlag(value) {
+			if i := bytes.Index(content, []byte(encoded.text)); i >= 0 {
+				report(i, encoded.text, LeakHigh, fmt.Sprintf("contains the flag of this challenge encoded as %s", encoded.name))
+			}
+		}
+	}
+
+	for _, pattern := range s.patterns {
+		for _, loc := range pattern.FindAllIndex(content, -1) {
+			match := string(content[loc[0]:loc[1]])
+			if _, known := s.flags[match]; known {
+				continue
+			}
+			report(loc[0], match, LeakMedium, fmt.Sprintf("contains flag-like string %q", redactFlag(match)))
+		}
+	}
+	return findings
+}
+
+// encodedFlag is a flag in one of the encodings checked by the scanner
+type encodedFlag struct {
+	name string
+	text string
+}
+
+// encodeFlag returns the common encodings of a flag that still give it away
+func encodeFlag(value string) []encodedFlag {
+	// Without padding, so that both padded and unpadded base64 are found
+	encodings := []encodedFlag{{"base64", base64.RawStdEncoding.EncodeToString([]byte(value))}}
+	if urlSafe := base64.RawURLEncoding.EncodeToString([]byte(value)); urlSafe != encodings[0].text {
+		encodings = append(encodings, encodedFlag{"base64url", urlSafe})
+	}
+	return append(encodings, encodedFlag{"hex", hex.EncodeToString([]byte(value))})
+}
+
+// lineAt returns the 1-based line of offset in content, or 0 for binary content
+func lineAt(content []byte, offset int) int {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	// Same heuristic as git: a NUL byte near the start means binary
+	if bytes.IndexByte(head, 0) >= 0 {
+		return 0
+	}
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
+
+// loadWorkingTreeTargets returns the challenges in the working tree with the
+// files under their public/ directories
+func loadWorkingTreeTargets(ctx context.Context, genres []string) ([]auditTarget, error) {
+	loader := &FileSystemLoader{}
+	challenges, err := loader.LoadChallenges(ctx, genres)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []auditTarget
+	for _, challenge := range challenges {
+		target := auditTarget{Challenge: challenge, Read: os.ReadFile}
+		public := filepath.Join(filepath.Dir(challenge.FilePath), "public")
+		files, err := walkPublicFiles(public)
+		if err != nil {
+			// The files found before the error are still scanned
+			fmt.Fprintf(os.Stderr, "Warning: Failed to list %s: %v\n", public, err)
+		}
+		target.Files = files
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// walkPublicFiles returns the regular files under a public/ directory in the
+// working tree. A missing directory has no files; an entry that cannot be
+// read stops the walk with its error.
+func walkPublicFiles(public string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(public, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == public && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// loadBranchTargets returns the challenges committed on a branch with the
+// files under their public/ directories, read with git
+func loadBranchTargets(ctx context.Context, branch string, genres []string) ([]auditTarget, error) {
+	loader := &GitBranchLoader{BranchName: branch}
+	challenges, err := loader.LoadChallenges(ctx, genres)
+	if err != nil {
+		return nil, err
+	}
+
+	read := func(file string) ([]byte, error) {
+		return getFileContentFromBranch(branch, file)
+	}
+
+	var targets []auditTarget
+	for _, challenge := range challenges {
+		target := auditTarget{Challenge: challenge, Read: read}
+		public := path.Join(path.Dir(challenge.FilePath), "public")
+		if files, err := listFilesInBranch(branch, public); err == nil {
+			for _, file := range files {
+				target.Files = append(target.Files, path.Join(public, file))
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// auditTargets scans the public files of targets and returns the findings.
+// It stops with the context's error when ctx is cancelled.
+func auditTargets(ctx context.Context, scanner *leakScanner, targets []auditTarget) ([]LeakFinding, error) {
+	var findings []LeakFinding
+	for _, target := range targets {
+		for _, file := range target.Files {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			content, err := target.Read(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to read %s: %v\n", file, err)
+				continue
+			}
+			for _, finding := range scanner.scan(content, target.Challenge) {
+				finding.Branch = target.Challenge.BranchName
+				finding.Path = filepath.ToSlash(file)
+				finding.Challenge = target.Challenge.Name
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// dropWorkingTreeDuplicates removes the findings on the current branch that
+// are also found in the working tree, where a committed public file would
+// otherwise be reported twice. Findings only in the committed copy are kept.
+func dropWorkingTreeDuplicates(findings []LeakFinding, current string) []LeakFinding {
+	if current == "" {
+		return findings
+	}
+
+	workingTree := make(map[LeakFinding]bool)
+	for _, finding := range findings {
+		if finding.Branch == "" {
+			workingTree[finding] = true
+		}
+	}
+
+	var result []LeakFinding
+	for _, finding := range findings {
+		if finding.Branch == current {
+			duplicate := finding
+			duplicate.Branch = ""
+			if workingTree[duplicate] {
+				continue
+			}
+		}
+		result = append(result, finding)
+	}
+	return result
+}
+
+// sortLeakFindings orders findings by severity, most serious first, then by location
+func sortLeakFindings(findings []LeakFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Branch != b.Branch {
+			return a.Branch < b.Branch
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+}
+
+// runAuditCommand implements `searchall audit`, which looks for flags in the
+// public/ files of the working tree and of every local branch. Leaks found in
+// both the working tree and the current branch are reported once. It reports
+// whether a finding reached the --fail-on severity.
+func runAuditCommand(args []string) (bool, error) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	skipBranches := flags.Bool("skip-branches", false, "Scan only the working tree")
+	failOnFlag := flags.String("fail-on", "high", "Lowest severity that makes the command fail: critical, high or medium")
+	formatFlag := flags.String("format", "text", "Output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+
+	failOn, err := parseLeakSeverity(*failOnFlag)
+	if err != nil {
+		return false, err
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		return false, fmt.Errorf("unknown output format %q (expected text or json)", *formatFlag)
+	}
+
+	config, err := loadConfig("config.yaml")
+	if err != nil {
+		return false, fmt.Errorf("failed to load config.yaml: %w", err)
+	}
+
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	targets, err := loadWorkingTreeTargets(ctx, config.Genre)
+	if err != nil {
+		return false, fmt.Errorf("failed to load challenges: %w", err)
+	}
+	if !*skipBranches {
+		branches, err := listLocalBranches()
+		if err != nil {
+			return false, err
+		}
+		for _, branch := range branches {
+			branchTargets, err := loadBranchTargets(ctx, branch, config.Genre)
+			if err != nil {
+				return false, fmt.Errorf("failed to load challenges from branch %s: %w", branch, err)
+			}
+			targets = append(targets, branchTargets...)
+		}
+	}
+
+	scanner, err := newLeakScanner(targets, config.Audit)
+	if err != nil {
+		return false, err
+	}
+	findings, err := auditTargets(ctx, scanner, targets)
+	if err != nil {
+		return false, err
+	}
+	if !*skipBranches {
+		current, err := getCurrentBranch()
+		if err != nil {
+			return false, err
+		}
+		findings = dropWorkingTreeDuplicates(findings, current)
+	}
+	sortLeakFindings(findings)
+
+	if *formatFlag == "json" {
+		if err := writeLeakFindingsJSON(os.Stdout, findings); err != nil {
+			return false, err
+		}
+	} else {
+		for _, finding := range findings {
+			fmt.Println(finding)
+		}
+	}
+
+	failed := false
+	for _, finding := range findings {
+		failed = failed || finding.Severity >= failOn
+	}
+	return failed, nil
+}
+
+// writeLeakFindingsJSON writes the findings as a versioned JSON document
+func writeLeakFindingsJSON(w io.Writer, findings []LeakFinding) error {
+	if findings == nil {
+		findings = []LeakFinding{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Version  int           `json:"version"`
+		Findings []LeakFinding `json:"findings"`
+	}{auditSchemaVersion, findings})
+}
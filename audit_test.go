@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func auditTestTargets(files map[string]string) []auditTarget {
+	read := func(path string) ([]byte, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("no such file %s", path)
+		}
+		return []byte(content), nil
+	}
+	return []auditTarget{
+		{
+			Challenge: ChallengeResult{Name: "Web", Flag: "flag{web_secret}", FilePath: "web/a/challenge.yml"},
+			Files:     []string{"web/a/public/notes.txt", "web/a/public/dump.bin"},
+			Read:      read,
+		},
+		{
+			Challenge: ChallengeResult{Name: "OSINT", Flag: "ctf{osint_secret}", FilePath: "osint/b/challenge.yml", BranchName: "feature/osint"},
+			Files:     []string{"osint/b/public/readme.md"},
+			Read:      read,
+		},
+	}
+}
+
+func TestLeakScannerScan(t *testing.T) {
+	scanner, err := newLeakScanner(auditTestTargets(nil), AuditConfig{Allow: []string{"flag{example}"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	web := ChallengeResult{Name: "Web", Flag: "flag{web_secret}"}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{"clean file", "nothing to see here\n", nil},
+		{"own flag", "intro\nthe answer is flag{web_secret}\n", []string{"critical:2:flag{web…"}},
+		{"other flag", "ctf{osint_secret}", []string{"high:1:ctf{osi…"}},
+		{"base64 flag", "ZmxhZ3t3ZWJfc2VjcmV0fQ==", []string{"high:1:Zmx…"}},
+		{"hex flag", "x=666c61677b7765625f7365637265747d", []string{"high:1:666…"}},
+		{"flag-like string", "a\nb\nFLAG{guess}\nFLAG{guess}\n", []string{"medium:3:FLAG{gu…"}},
+		{"prefix of a known flag", "CTF{other}", []string{"medium:1:CTF{ot…"}},
+		{"allowed decoy", "try flag{example}", nil},
+		{"binary file", "\x00\x01\nflag{web_secret}", []string{"critical:0:flag{web…"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, finding := range scanner.scan([]byte(tt.content), web) {
+			got = append(got, fmt.Sprintf("%s:%d:%s", finding.Severity, finding.Line, finding.Match))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestRedactFlag(t *testing.T) {
+	tests := []struct {
+		match    string
+		expected string
+	}{
+		{"flag{web_secret}", "flag{web…"},
+		{"FLAG{ab}", "FLAG{a…"},
+		{"flag{a}", "flag{…"},
+		{"flag{}", "flag{…"},
+		{"ZmxhZ3t3ZWJ9", "Zmx…"},
+		{"ひみつのフラグ{日本語のフラグ}", "ひみつのフラグ{日本語…"},
+	}
+
+	for _, tt := range tests {
+		if got := redactFlag(tt.match); got != tt.expected {
+			t.Errorf("redactFlag(%q): expected %q, got %q", tt.match, tt.expected, got)
+		}
+	}
+}
+
+func TestNewLeakScannerInvalidPattern(t *testing.T) {
+	if _, err := newLeakScanner(nil, AuditConfig{FlagPatterns: []string{"("}}); err == nil {
+		t.Error("Expected error for an invalid flag pattern")
+	}
+}
+
+func TestAuditTargets(t *testing.T) {
+	targets := auditTestTargets(map[string]string{
+		"web/a/public/notes.txt":   "flag{web_secret}\nctf{osint_secret}\n",
+		"web/a/public/dump.bin":    "\x00flag{maybe}",
+		"osint/b/public/readme.md": "# Readme\n\nctf{osint_secret}\n",
+	})
+	scanner, err := newLeakScanner(targets, AuditConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	findings, err := auditTargets(context.Background(), scanner, targets)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sortLeakFindings(findings)
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.String())
+	}
+	expected := []string{
+		"critical: web/a/public/notes.txt:1: contains the flag of this challenge",
+		"critical: [feature/osint] osint/b/public/readme.md:3: contains the flag of this challenge",
+		"high: web/a/public/notes.txt:2: contains the flag of \"OSINT\"",
+		"medium: web/a/public/dump.bin: contains flag-like string \"flag{ma…\"",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestDropWorkingTreeDuplicates(t *testing.T) {
+	leak := LeakFinding{Severity: LeakCritical, Path: "web/a/public/notes.txt", Line: 1, Challenge: "A", Match: "flag{we…", Message: "contains the flag of this challenge"}
+	onBranch := func(branch string, line int) LeakFinding {
+		finding := leak
+		finding.Branch, finding.Line = branch, line
+		return finding
+	}
+	findings := []LeakFinding{
+		leak,
+		onBranch("main", 1),    // Same file committed on the current branch
+		onBranch("main", 3),    // Committed copy differs from the working tree
+		onBranch("feature", 1), // Another branch is still reported
+	}
+
+	got := dropWorkingTreeDuplicates(findings, "main")
+	expected := []LeakFinding{leak, onBranch("main", 3), onBranch("feature", 1)}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// Without a current branch (detached HEAD) nothing is dropped
+	if got := dropWorkingTreeDuplicates(findings, ""); len(got) != len(findings) {
+		t.Errorf("Expected every finding to be kept, got %v", got)
+	}
+}
+
+func TestLoadWorkingTreeTargets(t *testing.T) {
+	writeLintTree(t, map[string]string{
+		"web/a/challenge.yml":        "name: A\nflag: flag{a}\ntags: [easy]\n",
+		"web/a/public/x.txt":         "x",
+		"web/a/public/nested/y.txt":  "y",
+		"web/b/challenge.yml":        "name: B\nflag: flag{b}\ntags: [easy]\n",
+		"web/b/src/not_public.txt":   "flag{b}",
+		"osint/c/challenge.yml":      "name: C\ntags: [easy]\n",
+		"osint/c/public/c.txt":       "c",
+		"misc/ignored/challenge.yml": "name: Ignored\n",
+	})
+
+	targets, err := loadWorkingTreeTargets(context.Background(), []string{"web", "osint"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files := make(map[string][]string)
+	for _, target := range targets {
+		files[target.Challenge.Name] = target.Files
+	}
+	expected := map[string][]string{
+		"A": {"web/a/public/nested/y.txt", "web/a/public/x.txt"},
+		"B": nil,
+		"C": {"osint/c/public/c.txt"},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected public files %v, got %v", expected, files)
+	}
+}
+
+func TestWalkPublicFiles(t *testing.T) {
+	dir := t.TempDir()
+	if files, err := walkPublicFiles(filepath.Join(dir, "public")); files != nil || err != nil {
+		t.Errorf("Expected a missing public/ to have no files, got %v, %v", files, err)
+	}
+	// Errors other than a missing directory are returned, not taken for "no files"
+	if _, err := walkPublicFiles(filepath.Join(dir, "bad\x00name")); err == nil {
+		t.Error("Expected an error for a directory that cannot be read")
+	}
+}
+
+func TestParseLeakSeverity(t *testing.T) {
+	for name, expected := range map[string]LeakSeverity{"critical": LeakCritical, "High": LeakHigh, "medium": LeakMedium} {
+		if got, err := parseLeakSeverity(name); err != nil || got != expected {
+			t.Errorf("parseLeakSeverity(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := parseLeakSeverity("low"); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
+
+func TestWriteLeakFindingsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	findings := []LeakFinding{{Severity: LeakCritical, Path: "web/a/public/x.txt", Line: 1, Challenge: "A", Match: "flag{a}", Message: "contains the flag of this challenge"}}
+	if err := writeLeakFindingsJSON(&buf, findings); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Version  int `json:"version"`
+		Findings []struct {
+			Severity string `json:"severity"`
+			Path     string `json:"path"`
+		} `json:"findings"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Version != auditSchemaVersion || len(doc.Findings) != 1 || doc.Findings[0].Severity != "critical" {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+
+	buf.Reset()
+	if err := writeLeakFindingsJSON(&buf, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"findings": []`) {
+		t.Errorf("Expected an empty findings list, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// diffSchemaVersion is the version of the JSON written by `searchall diff-branches --format json`
+const diffSchemaVersion = 1
+
+// Status of a challenge in ChallengeDiff
+const (
+	DiffChanged = "changed" // The challenge differs between the branches
+	DiffAdded   = "added"   // The challenge exists only on Branch
+	DiffRemoved = "removed" // The challenge exists only on Base
+)
+
+// FieldDiff is a challenge.yml field whose value differs between two branches
+type FieldDiff struct {
+	Field   string   `json:"field"`
+	Old     string   `json:"old"`
+	New     string   `json:"new"`
+	Added   []string `json:"added,omitempty"`   // Tags only on the new version
+	Removed []string `json:"removed,omitempty"` // Tags only on the old version
+}
+
+// ChallengeDiff describes how a challenge on Branch differs from the one on Base
+type ChallengeDiff struct {
+	Path   string      `json:"path"`
+	Name   string      `json:"name"`
+	Base   string      `json:"base"`
+	Branch string      `json:"branch"`
+	Status string      `json:"status"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// diffChallenges compares two versions of a challenge field by field. Tags
+// are compared as a set, so reordering them is not a difference. Flags are
+// compared in full but shortened by redactDiffFlag, since diffs are printed
+// in CI logs.
+func diffChallenges(before, after *Challenge) []FieldDiff {
+	var diffs []FieldDiff
+	for _, field := range []struct {
+		name          string
+		before, after string
+	}{
+		{"name", before.Name, after.Name},
+		{"description", before.Description, after.Description},
+		{"author", before.Author, after.Author},
+		{"flag", before.Flag, after.Flag},
+	} {
+		if field.before == field.after {
+			continue
+		}
+		diff := FieldDiff{Field: field.name, Old: field.before, New: field.after}
+		if field.name == "flag" {
+			diff.Old, diff.New = redactDiffFlag(field.before), redactDiffFlag(field.after)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	added, removed := diffTags(before.Tags, after.Tags)
+	if len(added) > 0 || len(removed) > 0 {
+		diffs = append(diffs, FieldDiff{
+			Field:   "tags",
+			Old:     strings.Join(before.Tags, ", "),
+			New:     strings.Join(after.Tags, ", "),
+			Added:   added,
+			Removed: removed,
+		})
+	}
+
+	// Other keys of challenge.yml, in key order
+	keys := make(map[string]bool)
+	for key := range before.Extra {
+		keys[key] = true
+	}
+	for key := range after.Extra {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		beforeValue, afterValue := before.Extra[key], after.Extra[key]
+		if !reflect.DeepEqual(beforeValue, afterValue) {
+			diffs = append(diffs, FieldDiff{Field: key, Old: formatExtraValue(beforeValue), New: formatExtraValue(afterValue)})
+		}
+	}
+	return diffs
+}
+
+// redactDiffFlag shortens a flag for a diff, keeping a missing flag empty.
+// The redacted prefix is followed by a short hash of the full flag, so that
+// two flags sharing a prefix can still be told apart.
+func redactDiffFlag(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%s [%x]", redactFlag(value), sum[:3])
+}
+
+// diffTags returns the tags only in after and the tags only in before
+func diffTags(before, after []string) (added, removed []string) {
+	for _, tag := range after {
+		if !containsFold(before, tag) && !containsFold(added, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range before {
+		if !containsFold(after, tag) && !containsFold(removed, tag) {
+			removed = append(removed, tag)
+		}
+	}
+	return added, removed
+}
+
+// formatExtraValue renders a value of an extra key as JSON, or "" when absent
+func formatExtraValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// diffBranches compares the challenges of two branches, including those that
+// exist on only one of them. Both branches must exist; a missing genre
+// directory only means that the branch has no challenges in it.
+func diffBranches(ctx context.Context, repo GitRepository, base, branch string, genres []string, index *ChallengeIndex) ([]ChallengeDiff, error) {
+	repo = repositoryOr(repo)
+	for _, name := range []string{base, branch} {
+		if _, err := repo.TreeID(name, ""); err != nil {
+			return nil, fmt.Errorf("unknown branch %q", name)
+		}
+	}
+
+	baseChallenges, err := (&GitBranchLoader{BranchName: base, Git: repo, Index: index}).LoadChallenges(ctx, genres)
+	if err != nil {
+		return nil, err
+	}
+	branchChallenges, err := (&GitBranchLoader{BranchName: branch, Git: repo, Index: index}).LoadChallenges(ctx, genres)
+	if err != nil {
+		return nil, err
+	}
+	return compareChallengeSets(base, baseChallenges, branch, branchChallenges), nil
+}
+
+// compareChallengeSets pairs challenges by path and diffs each pair
+func compareChallengeSets(base string, baseChallenges []ChallengeResult, branch string, branchChallenges []ChallengeResult) []ChallengeDiff {
+	byPath := make(map[string]ChallengeResult)
+	for _, challenge := range branchChallenges {
+		byPath[challenge.FilePath] = challenge
+	}
+
+	var diffs []ChallengeDiff
+	for _, before := range baseChallenges {
+		after, ok := byPath[before.FilePath]
+		if !ok {
+			diffs = append(diffs, ChallengeDiff{Path: before.FilePath, Name: before.Name, Base: base, Branch: branch, Status: DiffRemoved})
+			continue
+		}
+		delete(byPath, before.FilePath)
+		if fields := diffChallenges(challengeOf(before), challengeOf(after)); len(fields) > 0 {
+			diffs = append(diffs, ChallengeDiff{Path: before.FilePath, Name: before.Name, Base: base, Branch: branch, Status: DiffChanged, Fields: fields})
+		}
+	}
+	for _, after := range branchChallenges {
+		if _, ok := byPath[after.FilePath]; ok {
+			diffs = append(diffs, ChallengeDiff{Path: after.FilePath, Name: after.Name, Base: base, Branch: branch, Status: DiffAdded})
+		}
+	}
+
+	sortChallengeDiffs(diffs)
+	return diffs
+}
+
+// challengeOf converts a result back into the challenge.yml fields
+func challengeOf(result ChallengeResult) *Challenge {
+	return &Challenge{
+		Name:        result.Name,
+		Description: result.Description,
+		Author:      result.Author,
+		Flag:        result.Flag,
+		Tags:        result.Tags,
+		Extra:       result.Extra,
+	}
+}
+
+// sortChallengeDiffs orders diffs by path, then by branch
+func sortChallengeDiffs(diffs []ChallengeDiff) {
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].Path != diffs[j].Path {
+			return diffs[i].Path < diffs[j].Path
+		}
+		return diffs[i].Branch < diffs[j].Branch
+	})
+}
+
+// conflictsFor keeps the conflicts concerning the given results
+func conflictsFor(conflicts []ChallengeDiff, results []ChallengeResult) []ChallengeDiff {
+	paths := make(map[string]bool)
+	for _, result := range results {
+		paths[result.FilePath] = true
+	}
+
+	var filtered []ChallengeDiff
+	for _, conflict := range conflicts {
+		if paths[conflict.Path] {
+			filtered = append(filtered, conflict)
+		}
+	}
+	return filtered
+}
+
+// writeChallengeDiffs writes diffs as text, one challenge per block:
+//
+//	web/chall_3/challenge.yml "SQL Injection Basics": main -> feature/sqli
+//	  flag: "flag{old…" -> "flag{new…"
+//	  tags: +hard -easy
+func writeChallengeDiffs(w io.Writer, diffs []ChallengeDiff) {
+	for _, diff := range diffs {
+		switch diff.Status {
+		case DiffAdded:
+			fmt.Fprintf(w, "%s %q: only in %s\n", diff.Path, diff.Name, diff.Branch)
+		case DiffRemoved:
+			fmt.Fprintf(w, "%s %q: only in %s\n", diff.Path, diff.Name, diff.Base)
+		default:
+			fmt.Fprintf(w, "%s %q: %s -> %s\n", diff.Path, diff.Name, diff.Base, diff.Branch)
+		}
+
+		for _, field := range diff.Fields {
+			if field.Field == "tags" {
+				var changes []string
+				for _, tag := range field.Added {
+					changes = append(changes, "+"+tag)
+				}
+				for _, tag := range field.Removed {
+					changes = append(changes, "-"+tag)
+				}
+				fmt.Fprintf(w, "  tags: %s\n", strings.Join(changes, " "))
+				continue
+			}
+			fmt.Fprintf(w, "  %s: %s -> %s\n", field.Field, formatDiffValue(field.Old), formatDiffValue(field.New))
+		}
+	}
+}
+
+// reportConflicts writes the conflicts found by --show-conflicts, if any, under a heading
+func reportConflicts(w io.Writer, conflicts []ChallengeDiff) {
+	if len(conflicts) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Conflicting versions across branches:")
+	writeChallengeDiffs(w, conflicts)
+}
+
+// formatDiffValue quotes a value for the text diff, showing a missing value as (none)
+func formatDiffValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// writeChallengeDiffsJSON writes the diffs as a versioned JSON document
+func writeChallengeDiffsJSON(w io.Writer, diffs []ChallengeDiff) error {
+	if diffs == nil {
+		diffs = []ChallengeDiff{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Version int             `json:"version"`
+		Diffs   []ChallengeDiff `json:"diffs"`
+	}{diffSchemaVersion, diffs})
+}
+
+// runDiffBranchesCommand implements `searchall diff-branches [base branch]`.
+// With two branches it compares them; without, it reports every challenge
+// whose copy on a branch differs from the one --all-branches would show. With
+// --exit-code it reports whether any difference was found.
+func runDiffBranchesCommand(args []string) (bool, error) {
+	flags := flag.NewFlagSet("diff-branches", flag.ExitOnError)
+	formatFlag := flags.String("format", "text", "Output format: text or json")
+	exitCode := flags.Bool("exit-code", false, "Exit with status 1 when differences are found")
+	jobs := flags.Int("jobs", 0, "Branches and files loaded in parallel when comparing all branches (0: one per CPU)")
+	refsFlags := addRefFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+	refs, err := refsFlags()
+	if err != nil {
+		return false, err
+	}
+
+	if *formatFlag != "text" && *formatFlag != "json" {
+		return false, fmt.Errorf("unknown output format %q (expected text or json)", *formatFlag)
+	}
+	if flags.NArg() != 0 && flags.NArg() != 2 {
+		return false, fmt.Errorf("expected two branches to compare, or none to compare all branches")
+	}
+	if flags.NArg() == 2 && !refs.IsZero() {
+		return false, fmt.Errorf("--refs and --exclude-refs only apply when comparing all branches")
+	}
+
+	config, err := loadConfig("config.yaml")
+	if err != nil {
+		return false, fmt.Errorf("failed to load config.yaml: %w", err)
+	}
+
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	var diffs []ChallengeDiff
+	if flags.NArg() == 2 {
+		diffs, err = diffBranches(ctx, nil, flags.Arg(0), flags.Arg(1), config.Genre, openDefaultIndex())
+		if err != nil {
+			return false, err
+		}
+	} else {
+		loader, err := newMultiBranchLoader(refs, config.Branches, *jobs)
+		if err != nil {
+			return false, err
+		}
+		loader.DetectConflicts = true
+		loader.Index = openDefaultIndex()
+		if _, err := loader.LoadChallenges(ctx, config.Genre); err != nil {
+			return false, fmt.Errorf("failed to load challenges: %w", err)
+		}
+		diffs = loader.Conflicts
+	}
+
+	if *formatFlag == "json" {
+		if err := writeChallengeDiffsJSON(os.Stdout, diffs); err != nil {
+			return false, err
+		}
+	} else {
+		writeChallengeDiffs(os.Stdout, diffs)
+	}
+	return *exitCode && len(diffs) > 0, nil
+}
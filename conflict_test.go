@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffChallenges(t *testing.T) {
+	before := &Challenge{
+		Name:   "SQL Injection Basics",
+		Author: "Web Security Team",
+		Flag:   "flag{old}",
+		Tags:   []string{"easy", "web", "sql-injection"},
+		Extra:  map[string]interface{}{"points": 100, "type": "standard"},
+	}
+
+	tests := []struct {
+		name     string
+		after    Challenge
+		expected []FieldDiff
+	}{
+		{
+			name:     "identical",
+			after:    *before,
+			expected: nil,
+		},
+		{
+			name: "reordered tags",
+			after: Challenge{Name: before.Name, Author: before.Author, Flag: before.Flag,
+				Tags: []string{"web", "sql-injection", "easy"}, Extra: before.Extra},
+			expected: nil,
+		},
+		{
+			name: "flag and tags",
+			after: Challenge{Name: before.Name, Author: before.Author, Flag: "flag{new}",
+				Tags: []string{"hard", "web", "sql-injection"}, Extra: before.Extra},
+			expected: []FieldDiff{
+				{Field: "flag", Old: redactDiffFlag("flag{old}"), New: redactDiffFlag("flag{new}")},
+				{Field: "tags", Old: "easy, web, sql-injection", New: "hard, web, sql-injection", Added: []string{"hard"}, Removed: []string{"easy"}},
+			},
+		},
+		{
+			name: "extra keys",
+			after: Challenge{Name: before.Name, Author: before.Author, Flag: before.Flag, Tags: before.Tags,
+				Extra: map[string]interface{}{"points": 200, "hints": []interface{}{"look closer"}}},
+			expected: []FieldDiff{
+				{Field: "hints", Old: "", New: `["look closer"]`},
+				{Field: "points", Old: "100", New: "200"},
+				{Field: "type", Old: `"standard"`, New: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := diffChallenges(before, &tt.after); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestCompareChallengeSets(t *testing.T) {
+	base := []ChallengeResult{
+		{Name: "A", FilePath: "web/a/challenge.yml", Flag: "flag{a}"},
+		{Name: "B", FilePath: "web/b/challenge.yml", Flag: "flag{b}"},
+		{Name: "C", FilePath: "osint/c/challenge.yml"},
+	}
+	branch := []ChallengeResult{
+		{Name: "A", FilePath: "web/a/challenge.yml", Flag: "flag{a}"},
+		{Name: "B", FilePath: "web/b/challenge.yml", Flag: "flag{b2}"},
+		{Name: "D", FilePath: "osint/d/challenge.yml"},
+	}
+
+	diffs := compareChallengeSets("main", base, "feature", branch)
+
+	var got []string
+	for _, diff := range diffs {
+		got = append(got, diff.Status+" "+diff.Path)
+	}
+	expected := []string{"removed osint/c/challenge.yml", "added osint/d/challenge.yml", "changed web/b/challenge.yml"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiffBranches(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main":    {"web/a/challenge.yml": "name: A\nflag: flag{alpha_secret}\n"},
+		"feature": {"web/a/challenge.yml": "name: A\nflag: flag{beta_secret}\n", "misc/b/challenge.yml": "name: B\n"},
+	})
+	ctx := context.Background()
+
+	diffs, err := diffBranches(ctx, repo, "main", "feature", []string{"web", "misc"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, diff := range diffs {
+		got = append(got, diff.Status+" "+diff.Path)
+	}
+	expected := []string{"added misc/b/challenge.yml", "changed web/a/challenge.yml"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// Flags never appear in full in the diff, which is printed in CI logs
+	var buf bytes.Buffer
+	writeChallengeDiffs(&buf, diffs)
+	if err := writeChallengeDiffsJSON(&buf, diffs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "secret") || !strings.Contains(buf.String(), `flag: "flag{alp… [`) {
+		t.Errorf("Expected the flags to be redacted, got:\n%s", buf.String())
+	}
+
+	// An unknown branch is an error, not a branch without challenges
+	for _, args := range [][2]string{{"main", "nosuch"}, {"nosuch", "feature"}} {
+		_, err := diffBranches(ctx, repo, args[0], args[1], []string{"web"}, nil)
+		if err == nil || !strings.Contains(err.Error(), `"nosuch"`) {
+			t.Errorf("diffBranches(%s, %s): expected an unknown branch error, got %v", args[0], args[1], err)
+		}
+	}
+}
+
+func TestRedactDiffFlag(t *testing.T) {
+	// Flags sharing more than the redacted prefix must still differ in a diff
+	before, after := redactDiffFlag("flag{sql_injection_v1}"), redactDiffFlag("flag{sql_injection_v2}")
+	if before == after {
+		t.Errorf("Expected different redactions, got %q for both", before)
+	}
+	for _, redacted := range []string{before, after} {
+		if !strings.HasPrefix(redacted, "flag{sql… [") || strings.Contains(redacted, "injection") {
+			t.Errorf("Expected a redacted flag with a hash, got %q", redacted)
+		}
+	}
+	if redactDiffFlag("flag{sql_injection_v1}") != before {
+		t.Error("Expected the redaction to be stable")
+	}
+	if got := redactDiffFlag(""); got != "" {
+		t.Errorf("Expected a missing flag to stay empty, got %q", got)
+	}
+}
+
+func TestWriteChallengeDiffs(t *testing.T) {
+	diffs := []ChallengeDiff{
+		{
+			Path: "web/chall_3/challenge.yml", Name: "SQL Injection Basics", Base: "main", Branch: "feature/sqli", Status: DiffChanged,
+			Fields: []FieldDiff{
+				{Field: "flag", Old: "flag{o…", New: "flag{n…"},
+				{Field: "tags", Added: []string{"hard"}, Removed: []string{"easy"}},
+				{Field: "points", Old: "", New: "100"},
+			},
+		},
+		{Path: "web/new/challenge.yml", Name: "New", Base: "main", Branch: "feature/sqli", Status: DiffAdded},
+		{Path: "web/old/challenge.yml", Name: "Old", Base: "main", Branch: "feature/sqli", Status: DiffRemoved},
+	}
+
+	var buf bytes.Buffer
+	writeChallengeDiffs(&buf, diffs)
+	expected := `web/chall_3/challenge.yml "SQL Injection Basics": main -> feature/sqli
+  flag: "flag{o…" -> "flag{n…"
+  tags: +hard -easy
+  points: (none) -> "100"
+web/new/challenge.yml "New": only in feature/sqli
+web/old/challenge.yml "Old": only in main
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	reportConflicts(&buf, nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output without conflicts, got %q", buf.String())
+	}
+}
+
+func TestConflictsFor(t *testing.T) {
+	conflicts := []ChallengeDiff{{Path: "web/a/challenge.yml"}, {Path: "web/b/challenge.yml"}}
+	results := []ChallengeResult{{FilePath: "web/b/challenge.yml"}}
+
+	if got := conflictsFor(conflicts, results); len(got) != 1 || got[0].Path != "web/b/challenge.yml" {
+		t.Errorf("Expected only the conflict of the result, got %v", got)
+	}
+}
+
+func TestWriteChallengeDiffsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeChallengeDiffsJSON(&buf, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Version int             `json:"version"`
+		Diffs   []ChallengeDiff `json:"diffs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Version != diffSchemaVersion || doc.Diffs == nil || len(doc.Diffs) != 0 {
+		t.Errorf("Expected an empty diffs list, got %+v", doc)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring weights for fuzzy matches, loosely modelled on fzf
+const (
+	fuzzyScoreMatch       = 16 // per matched rune
+	fuzzyBonusConsecutive = 8  // matched rune directly follows the previous one
+	fuzzyBonusBoundary    = 8  // matched rune starts a word
+	fuzzyPenaltyGap       = 1  // per unmatched rune between two matched runes
+	fuzzyPenaltyTypo      = 16 // per edit needed for an approximate match
+	fuzzyMaxTypos         = 2
+)
+
+// fuzzyMatch reports whether pattern fuzzily matches text, case-insensitively.
+// It first tries an fzf-style subsequence match and falls back to an
+// approximate substring match that tolerates a few typos (one per four
+// pattern runes, at most fuzzyMaxTypos). It returns a score (higher is better)
+// and the rune positions in text that matched.
+func fuzzyMatch(pattern, text string) (int, []int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, []int{}, true
+	}
+
+	if positions := subsequencePositions(p, t); positions != nil {
+		return scoreFuzzyPositions(t, positions), positions, true
+	}
+
+	maxTypos := min(len(p)/4, fuzzyMaxTypos)
+	if maxTypos == 0 {
+		return 0, nil, false
+	}
+
+	typos, positions := approximatePositions(p, t)
+	if typos > maxTypos {
+		return 0, nil, false
+	}
+
+	return max(1, scoreFuzzyPositions(t, positions)-typos*fuzzyPenaltyTypo), positions, true
+}
+
+// subsequencePositions finds the shortest window of t that contains p as a
+// subsequence and returns the matched positions, or nil if there is none
+func subsequencePositions(p, t []rune) []int {
+	// Forward scan to find where the first complete match ends
+	pi, end := 0, -1
+	for ti := range t {
+		if t[ti] == p[pi] {
+			pi++
+			if pi == len(p) {
+				end = ti
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return nil
+	}
+
+	// Backward scan from the end to tighten the start of the window
+	pi, start := len(p)-1, end
+	for ti := end; ti >= 0; ti-- {
+		if t[ti] == p[pi] {
+			pi--
+			if pi < 0 {
+				start = ti
+				break
+			}
+		}
+	}
+
+	positions := make([]int, 0, len(p))
+	pi = 0
+	for ti := start; ti <= end && pi < len(p); ti++ {
+		if t[ti] == p[pi] {
+			positions = append(positions, ti)
+			pi++
+		}
+	}
+	return positions
+}
+
+// approximatePositions computes the smallest number of edits (insertions,
+// deletions, substitutions and adjacent transpositions) that turn p into some
+// substring of t, and returns it with the positions of t that matched p exactly
+func approximatePositions(p, t []rune) (int, []int) {
+	m, n := len(p), len(t)
+	d := make([][]int, m+1)
+	for i := range d {
+		d[i] = make([]int, n+1)
+		d[i][0] = i
+	}
+
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			cost := 1
+			if p[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j-1]+cost, d[i-1][j]+1, d[i][j-1]+1)
+			if i > 1 && j > 1 && p[i-1] == t[j-2] && p[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	// The match may end anywhere in t
+	bestJ := 0
+	for j := 1; j <= n; j++ {
+		if d[m][j] < d[m][bestJ] {
+			bestJ = j
+		}
+	}
+
+	var positions []int
+	i, j := m, bestJ
+	for i > 0 && j > 0 {
+		switch {
+		case p[i-1] == t[j-1] && d[i][j] == d[i-1][j-1]:
+			positions = append(positions, j-1)
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && p[i-1] == t[j-2] && p[i-2] == t[j-1] && d[i][j] == d[i-2][j-2]+1:
+			positions = append(positions, j-1, j-2)
+			i, j = i-2, j-2
+		case d[i][j] == d[i-1][j-1]+1:
+			i, j = i-1, j-1
+		case d[i][j] == d[i-1][j]+1:
+			i--
+		default:
+			j--
+		}
+	}
+
+	sort.Ints(positions)
+	return d[m][bestJ], positions
+}
+
+// scoreFuzzyPositions rates a set of matched positions: consecutive runs and
+// word starts score higher, gaps between matched runes score lower
+func scoreFuzzyPositions(t []rune, positions []int) int {
+	score := 0
+	for k, pos := range positions {
+		score += fuzzyScoreMatch
+		if pos == 0 || !unicode.IsLetter(t[pos-1]) && !unicode.IsDigit(t[pos-1]) {
+			score += fuzzyBonusBoundary
+		}
+		if k > 0 {
+			if pos == positions[k-1]+1 {
+				score += fuzzyBonusConsecutive
+			} else {
+				score -= (pos - positions[k-1] - 1) * fuzzyPenaltyGap
+			}
+		}
+	}
+	return score
+}
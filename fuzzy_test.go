@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		text     string
+		expected bool
+	}{
+		{name: "Empty pattern", pattern: "", text: "web", expected: true},
+		{name: "Exact", pattern: "osint", text: "osint", expected: true},
+		{name: "Subsequence", pattern: "sqlinj", text: "sql-injection", expected: true},
+		{name: "Missing letter", pattern: "geolocaton", text: "geolocation", expected: true},
+		{name: "Transposed letters", pattern: "geolcoation", text: "geolocation", expected: true},
+		{name: "Substituted letter", pattern: "geolocatiom", text: "geolocation", expected: true},
+		{name: "Case-insensitive", pattern: "GEO", text: "geolocation", expected: true},
+		{name: "Short pattern needs subsequence", pattern: "xeb", text: "web", expected: false},
+		{name: "Too many typos", pattern: "gxoloxatixn", text: "geolocation", expected: false},
+		{name: "Unrelated", pattern: "crypto", text: "osint", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, ok := fuzzyMatch(tt.pattern, tt.text)
+			if ok != tt.expected {
+				t.Errorf("Expected %v for %q vs %q, got %v", tt.expected, tt.pattern, tt.text, ok)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatchPositions(t *testing.T) {
+	// The shortest window is preferred over the first occurrence
+	_, positions, ok := fuzzyMatch("ab", "a-xab")
+	if !ok {
+		t.Fatal("Expected match")
+	}
+	if !reflect.DeepEqual(positions, []int{3, 4}) {
+		t.Errorf("Expected positions [3 4], got %v", positions)
+	}
+
+	// Typo matches highlight only the runes that matched exactly
+	_, positions, ok = fuzzyMatch("geolocatiom", "geolocation")
+	if !ok {
+		t.Fatal("Expected match")
+	}
+	if !reflect.DeepEqual(positions, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("Expected positions 0-9, got %v", positions)
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	consecutive, _, _ := fuzzyMatch("geo", "geolocation")
+	scattered, _, _ := fuzzyMatch("geo", "green-mode")
+	typo, _, _ := fuzzyMatch("geolocatiom", "geolocation")
+	exact, _, _ := fuzzyMatch("geolocation", "geolocation")
+
+	if consecutive <= scattered {
+		t.Errorf("Expected consecutive match to score higher: %d <= %d", consecutive, scattered)
+	}
+	if typo >= exact {
+		t.Errorf("Expected typo match to score lower than exact: %d >= %d", typo, exact)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitRepository is the read access to git used by the branch loaders. It is
+// an interface so that tests can substitute an in-memory fake.
+type GitRepository interface {
+	// Branches returns the names of the local branches
+	Branches() ([]string, error)
+	// Refs returns the local branches, remote-tracking branches and tags
+	Refs() ([]Ref, error)
+	// CurrentBranch returns the name of the checked-out branch
+	CurrentBranch() (string, error)
+	// DefaultBranch returns the branch origin/HEAD points to, or "" if it is not set
+	DefaultBranch() (string, error)
+	// ListTree returns the files under dir on branch, recursively, with
+	// paths relative to dir. It fails if dir does not exist on the branch.
+	ListTree(branch, dir string) ([]TreeEntry, error)
+	// TreeID returns the object ID of dir on branch, which changes whenever
+	// a file below it does. It fails if dir does not exist on the branch.
+	TreeID(branch, dir string) (string, error)
+	// ReadBlob returns the content of a file by object ID
+	ReadBlob(oid string) ([]byte, error)
+	// ReadFile returns the content of a file on branch
+	ReadFile(branch, path string) ([]byte, error)
+	// CommitID returns the object ID of the commit branch points to
+	CommitID(branch string) (string, error)
+	// LastCommitTimes returns the time of the last commit on branch touching
+	// each of paths. Paths that no commit touches are left out.
+	LastCommitTimes(branch string, paths []string) (map[string]time.Time, error)
+}
+
+// TreeEntry is a file in a git tree
+type TreeEntry struct {
+	Path string // Relative to the listed directory, with forward slashes
+	OID  string // Object ID of the blob, which changes whenever the content does
+}
+
+// errObjectMissing is returned when git has no object by the requested name
+var errObjectMissing = errors.New("object not found")
+
+// catFileRepository reads objects through long-lived `git cat-file --batch`
+// processes instead of starting git for every file. Trees are parsed from
+// their raw objects, so listing a branch needs no extra processes either. It
+// is safe for concurrent use: each caller borrows an idle process, and up to
+// MaxProcesses are started when several goroutines read at once.
+type catFileRepository struct {
+	MaxProcesses int // 0 means one per CPU
+
+	once  sync.Once
+	slots chan struct{} // Holds a token for each process in use
+	mu    sync.Mutex
+	idle  []*catFileProcess
+}
+
+// catFileProcess is a running `git cat-file --batch`
+type catFileProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// defaultRepository is the repository in the current directory. The
+// cat-file process is started on first use.
+var defaultRepository GitRepository = &catFileRepository{}
+
+// repositoryOr returns repo, or defaultRepository when repo is nil
+func repositoryOr(repo GitRepository) GitRepository {
+	if repo != nil {
+		return repo
+	}
+	return defaultRepository
+}
+
+// Branches lists the local branches
+func (r *catFileRepository) Branches() ([]string, error) {
+	output, err := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	trimmed := strings.TrimSpace(string(output))
+	if trimmed == "" {
+		return []string{}, nil
+	}
+	return strings.Split(trimmed, "\n"), nil
+}
+
+// Refs lists the branches, remote-tracking branches and tags
+func (r *catFileRepository) Refs() ([]Ref, error) {
+	output, err := exec.Command("git", "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes", "refs/tags").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	refs := []Ref{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if ref, ok := newRef(line); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// CurrentBranch returns the checked-out branch
+func (r *catFileRepository) CurrentBranch() (string, error) {
+	output, err := exec.Command("git", "branch", "--show-current").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DefaultBranch reads the symbolic ref origin/HEAD, which clone sets to the
+// default branch of the remote
+func (r *catFileRepository) DefaultBranch() (string, error) {
+	output, err := exec.Command("git", "symbolic-ref", "--quiet", "refs/remotes/origin/HEAD").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// origin/HEAD does not exist, e.g. in a repository that was not cloned
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read origin/HEAD: %w", err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(output)), "refs/remotes/origin/"), nil
+}
+
+// ListTree walks the tree of branch:dir
+func (r *catFileRepository) ListTree(branch, dir string) ([]TreeEntry, error) {
+	oid, kind, data, err := r.object(branch + ":" + dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s in branch %s: %w", dir, branch, err)
+	}
+	if kind != "tree" {
+		return nil, fmt.Errorf("failed to list %s in branch %s: not a directory", dir, branch)
+	}
+
+	var entries []TreeEntry
+	if err := r.walkTree(data, len(oid)/2, "", &entries); err != nil {
+		return nil, fmt.Errorf("failed to list %s in branch %s: %w", dir, branch, err)
+	}
+	return entries, nil
+}
+
+// TreeID resolves branch:dir to its tree object
+func (r *catFileRepository) TreeID(branch, dir string) (string, error) {
+	oid, kind, _, err := r.object(branch + ":" + dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s in branch %s: %w", dir, branch, err)
+	}
+	if kind != "tree" {
+		return "", fmt.Errorf("failed to find %s in branch %s: not a directory", dir, branch)
+	}
+	return oid, nil
+}
+
+// walkTree appends the blobs of a raw tree object to entries, descending
+// into subtrees. Each entry is "<mode> <name>\x00<binary object ID>".
+func (r *catFileRepository) walkTree(data []byte, hashSize int, prefix string, entries *[]TreeEntry) error {
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if space < 0 || nul < space || nul+1+hashSize > len(data) {
+			return fmt.Errorf("malformed tree object")
+		}
+		mode := string(data[:space])
+		name := path.Join(prefix, string(data[space+1:nul]))
+		oid := hex.EncodeToString(data[nul+1 : nul+1+hashSize])
+		data = data[nul+1+hashSize:]
+
+		switch {
+		case mode == "40000":
+			_, _, subtree, err := r.object(oid)
+			if err != nil {
+				return err
+			}
+			if err := r.walkTree(subtree, hashSize, name, entries); err != nil {
+				return err
+			}
+		case mode == "160000":
+			// Submodules have no content in this repository
+		default:
+			*entries = append(*entries, TreeEntry{Path: name, OID: oid})
+		}
+	}
+	return nil
+}
+
+// ReadBlob reads a blob by object ID
+func (r *catFileRepository) ReadBlob(oid string) ([]byte, error) {
+	_, kind, data, err := r.object(oid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", oid, err)
+	}
+	if kind != "blob" {
+		return nil, fmt.Errorf("failed to read object %s: not a file", oid)
+	}
+	return data, nil
+}
+
+// ReadFile reads branch:path
+func (r *catFileRepository) ReadFile(branch, file string) ([]byte, error) {
+	_, kind, data, err := r.object(branch + ":" + file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file content from branch %s: %w", branch, err)
+	}
+	if kind != "blob" {
+		return nil, fmt.Errorf("failed to get file content from branch %s: %s is not a file", branch, file)
+	}
+	return data, nil
+}
+
+// CommitID resolves branch through cat-file, peeling annotated tags
+func (r *catFileRepository) CommitID(branch string) (string, error) {
+	oid, _, _, err := r.object(branch + "^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", branch, err)
+	}
+	return oid, nil
+}
+
+// LastCommitTimes runs a single git log, which cat-file cannot answer, over
+// the top-level directories of paths, and stops it once every path was seen
+func (r *catFileRepository) LastCommitTimes(branch string, paths []string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time, len(paths))
+	wanted := make(map[string]bool, len(paths))
+	var dirs []string
+	for _, file := range paths {
+		wanted[file] = true
+		dir, _, _ := strings.Cut(file, "/")
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(paths) == 0 {
+		return times, nil
+	}
+
+	// Each commit is a NUL-prefixed timestamp followed by the changed files
+	cmd := exec.Command("git", "-c", "core.quotePath=false", "log", "--format=%x00%ct", "--name-only", "--no-renames", branch, "--")
+	cmd.Args = append(cmd.Args, dirs...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit times in branch %s: %w", branch, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to get commit times in branch %s: %w", branch, err)
+	}
+
+	var current time.Time
+	scanner := bufio.NewScanner(stdout)
+	for len(times) < len(wanted) && scanner.Scan() {
+		line := scanner.Text()
+		if timestamp, ok := strings.CutPrefix(line, "\x00"); ok {
+			seconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				_ = cmd.Process.Kill()
+				_ = cmd.Wait()
+				return nil, fmt.Errorf("failed to parse commit time %q: %w", timestamp, err)
+			}
+			current = time.Unix(seconds, 0)
+			continue
+		}
+		if _, seen := times[line]; wanted[line] && !seen {
+			times[line] = current
+		}
+	}
+
+	if len(times) == len(wanted) {
+		// The rest of the history is not needed
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return times, nil
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to get commit times in branch %s: %w", branch, err)
+	}
+	return times, nil
+}
+
+// object asks cat-file for an object by name, e.g. an object ID or
+// "branch:path", and returns its ID, type and content
+func (r *catFileRepository) object(name string) (oid, kind string, data []byte, err error) {
+	if strings.ContainsAny(name, "\n") {
+		return "", "", nil, fmt.Errorf("invalid object name %q", name)
+	}
+
+	process, err := r.acquire()
+	if err != nil {
+		return "", "", nil, err
+	}
+	oid, kind, data, err = process.request(name)
+	// After other errors the process is in an unknown state, so it is not reused
+	r.release(process, err == nil || errors.Is(err, errObjectMissing))
+	return oid, kind, data, err
+}
+
+// acquire borrows an idle process, starting one if none is idle, and waits
+// while MaxProcesses are in use
+func (r *catFileRepository) acquire() (*catFileProcess, error) {
+	r.once.Do(func() {
+		max := r.MaxProcesses
+		if max < 1 {
+			max = runtime.NumCPU()
+		}
+		r.slots = make(chan struct{}, max)
+	})
+	r.slots <- struct{}{}
+
+	r.mu.Lock()
+	if n := len(r.idle); n > 0 {
+		process := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return process, nil
+	}
+	r.mu.Unlock()
+
+	process, err := startCatFile()
+	if err != nil {
+		<-r.slots
+		return nil, err
+	}
+	return process, nil
+}
+
+// release returns a borrowed process, or stops it if it cannot be reused
+func (r *catFileRepository) release(process *catFileProcess, reusable bool) {
+	if reusable {
+		r.mu.Lock()
+		r.idle = append(r.idle, process)
+		r.mu.Unlock()
+	} else {
+		process.stop()
+	}
+	<-r.slots
+}
+
+// Close stops the idle processes. The repository can still be used
+// afterwards; new processes are started when needed.
+func (r *catFileRepository) Close() error {
+	r.mu.Lock()
+	idle := r.idle
+	r.idle = nil
+	r.mu.Unlock()
+
+	for _, process := range idle {
+		process.stop()
+	}
+	return nil
+}
+
+// startCatFile launches a cat-file process
+func startCatFile() (*catFileProcess, error) {
+	cmd := exec.Command("git", "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return &catFileProcess{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// request writes one query and reads the reply "<oid> <type> <size>\n<content>\n"
+func (p *catFileProcess) request(name string) (oid, kind string, data []byte, err error) {
+	if _, err := io.WriteString(p.stdin, name+"\n"); err != nil {
+		return "", "", nil, fmt.Errorf("git cat-file: %w", err)
+	}
+
+	header, err := p.stdout.ReadString('\n')
+	if err != nil {
+		return "", "", nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	// "<name> missing", where the name may itself contain spaces
+	fields := strings.Fields(header)
+	if n := len(fields); n >= 2 && (fields[n-1] == "missing" || fields[n-1] == "ambiguous") {
+		return "", "", nil, errObjectMissing
+	}
+	if len(fields) != 3 {
+		return "", "", nil, fmt.Errorf("git cat-file: unexpected reply %q", header)
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", "", nil, fmt.Errorf("git cat-file: unexpected reply %q", header)
+	}
+
+	// The content is followed by a newline
+	data = make([]byte, size+1)
+	if _, err := io.ReadFull(p.stdout, data); err != nil {
+		return "", "", nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return fields[0], fields[1], data[:size], nil
+}
+
+// stop ends the process
+func (p *catFileProcess) stop() {
+	p.stdin.Close()
+	_ = p.cmd.Wait()
+}
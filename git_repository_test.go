@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeGitRepository is an in-memory GitRepository for tests. Branches named
+// origin/* are remote-tracking branches, and tags/* are tags.
+type fakeGitRepository struct {
+	current       string
+	defaultBranch string                       // Branch origin/HEAD points to, if any
+	branches      map[string]map[string]string // Branch to path to content
+	reads         atomic.Int32                 // Number of blobs read
+	modTimes      map[string]time.Time         // "branch:path" to last commit time
+	logs          atomic.Int32                 // Number of branches whose commit times were looked up
+	listFailures  atomic.Int32                 // Number of ListTree calls that fail before it works again
+}
+
+// newFakeGitRepository creates a fake repository with the given branches
+func newFakeGitRepository(current string, branches map[string]map[string]string) *fakeGitRepository {
+	return &fakeGitRepository{current: current, branches: branches}
+}
+
+// fakeOID hashes content like git does for blobs
+func fakeOID(content string) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("blob %d\x00%s", len(content), content)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *fakeGitRepository) Branches() ([]string, error) {
+	var branches []string
+	for branch := range f.branches {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+func (f *fakeGitRepository) Refs() ([]Ref, error) {
+	var refs []Ref
+	for branch := range f.branches {
+		fullName := "refs/heads/" + branch
+		if strings.HasPrefix(branch, "origin/") {
+			fullName = "refs/remotes/" + branch
+		} else if strings.HasPrefix(branch, "tags/") {
+			fullName = "refs/" + branch
+		}
+		if ref, ok := newRef(fullName); ok {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].FullName < refs[j].FullName })
+	return refs, nil
+}
+
+// branch maps a full ref name back to the key of branches
+func (f *fakeGitRepository) branch(name string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest
+		}
+	}
+	return name
+}
+
+func (f *fakeGitRepository) DefaultBranch() (string, error) {
+	return f.defaultBranch, nil
+}
+
+func (f *fakeGitRepository) CurrentBranch() (string, error) {
+	return f.current, nil
+}
+
+func (f *fakeGitRepository) ListTree(branch, dir string) ([]TreeEntry, error) {
+	if f.listFailures.Add(-1) >= 0 {
+		return nil, fmt.Errorf("failed to list %s in branch %s: cat-file exited", dir, branch)
+	}
+	return f.tree(branch, dir)
+}
+
+// tree lists branch:dir; unlike ListTree it never fails on purpose
+func (f *fakeGitRepository) tree(branch, dir string) ([]TreeEntry, error) {
+	files, ok := f.branches[f.branch(branch)]
+	if !ok {
+		return nil, fmt.Errorf("no branch %s", branch)
+	}
+
+	var entries []TreeEntry
+	for path, content := range files {
+		if rel := strings.TrimPrefix(path, dir+"/"); rel != path || dir == "" {
+			entries = append(entries, TreeEntry{Path: rel, OID: fakeOID(content)})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no directory %s in branch %s", dir, branch)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+func (f *fakeGitRepository) TreeID(branch, dir string) (string, error) {
+	// Like git, a tree ID resolves to itself while some branch has the tree
+	if _, ok := f.branches[f.branch(branch)]; !ok && dir == "" {
+		for name, files := range f.branches {
+			for file := range files {
+				for treeDir := path.Dir(file); treeDir != "."; treeDir = path.Dir(treeDir) {
+					if tree, err := f.TreeID(name, treeDir); err == nil && tree == branch {
+						return tree, nil
+					}
+				}
+			}
+		}
+	}
+
+	entries, err := f.tree(branch, dir)
+	if err != nil {
+		return "", err
+	}
+	var listing strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&listing, "%s %s\n", entry.OID, entry.Path)
+	}
+	return fakeOID(listing.String()), nil
+}
+
+func (f *fakeGitRepository) ReadBlob(oid string) ([]byte, error) {
+	for _, files := range f.branches {
+		for _, content := range files {
+			if fakeOID(content) == oid {
+				f.reads.Add(1)
+				return []byte(content), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no object %s", oid)
+}
+
+func (f *fakeGitRepository) ReadFile(branch, path string) ([]byte, error) {
+	content, ok := f.branches[f.branch(branch)][path]
+	if !ok {
+		return nil, fmt.Errorf("no file %s in branch %s", path, branch)
+	}
+	f.reads.Add(1)
+	return []byte(content), nil
+}
+
+// CommitID derives the commit from the branch content, so that it changes
+// whenever a file does
+func (f *fakeGitRepository) CommitID(branch string) (string, error) {
+	files, ok := f.branches[f.branch(branch)]
+	if !ok {
+		return "", fmt.Errorf("no branch %s", branch)
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var commit strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&commit, "%s %s\n", fakeOID(files[path]), path)
+	}
+	return fakeOID(commit.String()), nil
+}
+
+func (f *fakeGitRepository) LastCommitTimes(branch string, paths []string) (map[string]time.Time, error) {
+	f.logs.Add(1)
+	times := make(map[string]time.Time)
+	for _, path := range paths {
+		if modTime, ok := f.modTimes[f.branch(branch)+":"+path]; ok {
+			times[path] = modTime
+		}
+	}
+	return times, nil
+}
+
+func TestGitBranchLoaderWithFake(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {
+			"web/a/challenge.yml":        "name: A\ntags: [easy]\n",
+			"web/a/public/file.txt":      "data",
+			"web/b/nested/challenge.yml": "name: B\n",
+			"web/c/my_challenge.yml":     "name: Not a challenge\n",
+			"web/broken/challenge.yml":   "name: [\n",
+			"misc/d/challenge.yml":       "name: D\n",
+		},
+	})
+
+	loader := &GitBranchLoader{BranchName: "main", Git: repo}
+	challenges, err := loader.LoadChallenges(context.Background(), []string{"web", "osint"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, challenge := range challenges {
+		got = append(got, challenge.Name+" "+challenge.FilePath+" "+challenge.Genre+" "+challenge.BranchName)
+	}
+	expected := []string{"A web/a/challenge.yml web main", "B web/b/nested/challenge.yml web main"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestMultiBranchLoaderWithFake(t *testing.T) {
+	repo := newFakeGitRepository("feat/current", map[string]map[string]string{
+		"main": {
+			"web/a/challenge.yml": "name: A\nflag: flag{a}\n",
+		},
+		"feat/current": {
+			"web/a/challenge.yml": "name: A\nflag: flag{a}\n",
+			"web/b/challenge.yml": "name: B\ntags: [easy]\n",
+		},
+		"feat/other": {
+			"web/a/challenge.yml": "name: A\nflag: flag{changed}\n",
+			"web/b/challenge.yml": "name: B\ntags: [hard]\n",
+			"web/c/challenge.yml": "name: C\n",
+		},
+	})
+
+	loader := &MultiBranchLoader{CurrentBranch: "feat/current", Git: repo}
+	challenges, err := loader.LoadChallenges(context.Background(), []string{"web"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	branches := make(map[string]string)
+	for _, challenge := range challenges {
+		branches[challenge.Name] = challenge.BranchName
+	}
+	expected := map[string]string{"A": "main", "B": "feat/current", "C": "feat/other"}
+	if !reflect.DeepEqual(branches, expected) {
+		t.Errorf("Expected challenges from %v, got %v", expected, branches)
+	}
+	if reads := repo.reads.Load(); reads != 3 {
+		t.Errorf("Expected only the kept copies to be read, got %d reads", reads)
+	}
+	if loader.Conflicts != nil {
+		t.Errorf("Expected no conflicts without DetectConflicts, got %v", loader.Conflicts)
+	}
+
+	loader.DetectConflicts = true
+	if _, err := loader.LoadChallenges(context.Background(), []string{"web"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var conflicts []string
+	for _, conflict := range loader.Conflicts {
+		conflicts = append(conflicts, fmt.Sprintf("%s %s->%s %s", conflict.Path, conflict.Base, conflict.Branch, conflict.Fields[0].Field))
+	}
+	expectedConflicts := []string{"web/a/challenge.yml main->feat/other flag", "web/b/challenge.yml feat/current->feat/other tags"}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("Expected conflicts %v, got %v", expectedConflicts, conflicts)
+	}
+
+	loader.DetectConflicts = false
+	loader.AllCopies = true
+	if _, err := loader.LoadChallenges(context.Background(), []string{"web"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var copies []string
+	for _, c := range loader.Copies {
+		copies = append(copies, c.BranchName+" "+c.Name)
+	}
+	sort.Strings(copies)
+	expectedCopies := []string{"feat/current A", "feat/current B", "feat/other A", "feat/other B", "feat/other C", "main A"}
+	if !reflect.DeepEqual(copies, expectedCopies) {
+		t.Errorf("Expected copies %v, got %v", expectedCopies, copies)
+	}
+}
+
+func TestCatFileRepository(t *testing.T) {
+	repo := &catFileRepository{}
+	defer repo.Close()
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+
+	// The tree walk must agree with git ls-tree
+	entries, err := repo.ListTree(branch, "osint")
+	if err != nil {
+		t.Fatalf("Failed to list tree: %v", err)
+	}
+	output, err := exec.Command("git", "ls-tree", "-r", branch+":osint").Output()
+	if err != nil {
+		t.Fatalf("Failed to run git ls-tree: %v", err)
+	}
+	var expected []TreeEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		// "<mode> <type> <oid>\t<path>"
+		meta, path, _ := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		expected = append(expected, TreeEntry{Path: path, OID: fields[2]})
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %v, got %v", expected, entries)
+	}
+
+	// Reading by path and by object ID gives the same content
+	byPath, err := repo.ReadFile(branch, "osint/"+entries[0].Path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	byOID, err := repo.ReadBlob(entries[0].OID)
+	if err != nil {
+		t.Fatalf("Failed to read blob: %v", err)
+	}
+	if len(byPath) == 0 || string(byPath) != string(byOID) {
+		t.Errorf("Expected the same content by path and object ID, got %q and %q", byPath, byOID)
+	}
+
+	// Missing objects are errors but leave the process usable
+	if _, err := repo.ReadFile(branch, "no/such/file"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := repo.ReadFile("no such branch", "README.md"); err == nil {
+		t.Error("Expected error for missing branch")
+	}
+	if _, err := repo.ListTree(branch, "README.md"); err == nil {
+		t.Error("Expected error for listing a file")
+	}
+	if _, err := repo.ReadFile(branch, "osint"); err == nil {
+		t.Error("Expected error for reading a directory")
+	}
+	if _, err := repo.ReadBlob(entries[0].OID); err != nil {
+		t.Errorf("Expected reads to work after errors, got %v", err)
+	}
+
+	commit, err := repo.CommitID(branch)
+	if err != nil {
+		t.Fatalf("Failed to resolve the branch: %v", err)
+	}
+	if output, _ := exec.Command("git", "rev-parse", branch).Output(); strings.TrimSpace(string(output)) != commit {
+		t.Errorf("Expected commit %s, got %s", strings.TrimSpace(string(output)), commit)
+	}
+
+	// The single git log must agree with git log -1 for each file
+	files := []string{"osint/" + entries[0].Path, "README.md", "no/such/file"}
+	times, err := repo.LastCommitTimes(branch, files)
+	if err != nil {
+		t.Fatalf("Failed to get commit times: %v", err)
+	}
+	for _, file := range files[:2] {
+		output, err := exec.Command("git", "log", "-1", "--format=%ct", branch, "--", file).Output()
+		if err != nil {
+			t.Fatalf("Failed to run git log: %v", err)
+		}
+		if got := strconv.FormatInt(times[file].Unix(), 10); got != strings.TrimSpace(string(output)) {
+			t.Errorf("%s: expected commit time %s, got %s", file, strings.TrimSpace(string(output)), got)
+		}
+	}
+	if _, ok := times["no/such/file"]; ok {
+		t.Error("Expected no time for a file without commits")
+	}
+}
@@ -1,69 +1,72 @@
 package main
 
-import (
-	"fmt"
-	"os/exec"
-	"strings"
-)
+import "path"
 
 // listLocalBranches returns a list of all local branch names
 func listLocalBranches() ([]string, error) {
-	cmd := exec.Command("git", "branch", "--format=%(refname:short)")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to list branches: %w", err)
-	}
-
-	branches := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(branches) == 1 && branches[0] == "" {
-		return []string{}, nil
-	}
-
-	return branches, nil
+	return defaultRepository.Branches()
 }
 
 // getCurrentBranch returns the name of the current branch
 func getCurrentBranch() (string, error) {
-	cmd := exec.Command("git", "branch", "--show-current")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get current branch: %w", err)
-	}
-
-	return strings.TrimSpace(string(output)), nil
+	return defaultRepository.CurrentBranch()
 }
 
 // getFileContentFromBranch retrieves the content of a file from a specific branch
 func getFileContentFromBranch(branch, path string) ([]byte, error) {
-	cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", branch, path))
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file content from branch %s: %w", branch, err)
-	}
-
-	return output, nil
+	return defaultRepository.ReadFile(branch, path)
 }
 
 // listChallengeFilesInBranch lists all challenge.yml files in a specific branch under a genre directory
 func listChallengeFilesInBranch(branch, genre string) ([]string, error) {
-	// List all files in the genre directory tree
-	cmd := exec.Command("git", "ls-tree", "-r", "--name-only", fmt.Sprintf("%s:%s", branch, genre))
-	output, err := cmd.Output()
+	entries, err := listChallengeEntries(defaultRepository, branch, genre)
 	if err != nil {
-		// Genre directory might not exist in this branch
-		return []string{}, nil
+		return nil, err
+	}
+	files := make([]string, len(entries))
+	for i, entry := range entries {
+		files[i] = entry.Path
 	}
+	return files, nil
+}
+
+// listChallengeEntries lists the challenge.yml files under a genre directory
+// on a branch, with paths including the genre. A genre missing from the
+// branch has no challenges; a directory that cannot be listed is an error.
+func listChallengeEntries(repo GitRepository, branch, genre string) ([]TreeEntry, error) {
+	if _, err := repo.TreeID(branch, genre); err != nil {
+		return nil, nil
+	}
+	return listChallengeTree(repo, branch, genre)
+}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var challengeFiles []string
+// listChallengeTree lists the challenge.yml files under a genre directory
+// that is known to exist on the branch
+func listChallengeTree(repo GitRepository, branch, genre string) ([]TreeEntry, error) {
+	entries, err := repo.ListTree(branch, genre)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, line := range lines {
-		if strings.HasSuffix(line, "challenge.yml") {
-			// Construct full path: genre/...path.../challenge.yml
-			fullPath := fmt.Sprintf("%s/%s", genre, line)
-			challengeFiles = append(challengeFiles, fullPath)
+	var challenges []TreeEntry
+	for _, entry := range entries {
+		if path.Base(entry.Path) == "challenge.yml" {
+			challenges = append(challenges, TreeEntry{Path: path.Join(genre, entry.Path), OID: entry.OID})
 		}
 	}
+	return challenges, nil
+}
 
-	return challengeFiles, nil
+// listFilesInBranch lists all files under a directory in a specific branch, relative to that directory
+func listFilesInBranch(branch, dir string) ([]string, error) {
+	entries, err := defaultRepository.ListTree(branch, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, len(entries))
+	for i, entry := range entries {
+		files[i] = entry.Path
+	}
+	return files, nil
 }
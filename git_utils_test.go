@@ -87,3 +87,29 @@ func TestListChallengeFilesInBranch(t *testing.T) {
 		}
 	}
 }
+
+func TestListFilesInBranch(t *testing.T) {
+	currentBranch, err := getCurrentBranch()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+
+	files, err := listFilesInBranch(currentBranch, "osint/chall_1")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+
+	found := false
+	for _, file := range files {
+		if file == "public/sample_file.txt" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected public/sample_file.txt in %v", files)
+	}
+
+	if _, err := listFilesInBranch(currentBranch, "no/such/dir"); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
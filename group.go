@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// noGroup is the bucket for challenges that have no value for the facet
+const noGroup = "(none)"
+
+// builtinFacets are the --group-by facets that do not come from the taxonomy
+var builtinFacets = []string{"genre", "author", "branch", "tag"}
+
+// resultGroup is a bucket of results sharing a facet value
+type resultGroup struct {
+	Name    string
+	Results []ChallengeResult
+}
+
+// groupResults buckets results by a facet: genre, author, branch, tag, or a
+// top-level taxonomy entry. A challenge with several tags can appear in
+// several buckets. Taxonomy buckets follow the taxonomy order, the others are
+// sorted by name; the bucket for challenges without a value comes last.
+func groupResults(results []ChallengeResult, facet string, taxonomy *Taxonomy) ([]resultGroup, error) {
+	var order []string
+	var valuesOf func(c *ChallengeResult) []string
+
+	switch strings.ToLower(facet) {
+	case "genre":
+		valuesOf = func(c *ChallengeResult) []string { return []string{c.Genre} }
+	case "author":
+		valuesOf = func(c *ChallengeResult) []string { return []string{c.Author} }
+	case "branch":
+		valuesOf = func(c *ChallengeResult) []string { return []string{c.BranchName} }
+	case "tag":
+		valuesOf = func(c *ChallengeResult) []string { return c.Tags }
+	default:
+		if err := validateFacet(facet, taxonomy); err != nil {
+			return nil, err
+		}
+		order = taxonomy.facetValues(facet)
+		valuesOf = func(c *ChallengeResult) []string {
+			var values []string
+			for _, value := range order {
+				subtree := append([]string{value}, taxonomy.descendants(value)...)
+				for _, tag := range c.Tags {
+					if containsFold(subtree, tag) {
+						values = append(values, value)
+						break
+					}
+				}
+			}
+			return values
+		}
+	}
+
+	buckets := make(map[string][]ChallengeResult)
+	for i := range results {
+		values := valuesOf(&results[i])
+		seen := make(map[string]bool)
+		for _, value := range values {
+			if value == "" || seen[value] {
+				continue
+			}
+			seen[value] = true
+			buckets[value] = append(buckets[value], results[i])
+		}
+		if len(seen) == 0 {
+			buckets[noGroup] = append(buckets[noGroup], results[i])
+		}
+	}
+
+	if order == nil {
+		for name := range buckets {
+			if name != noGroup {
+				order = append(order, name)
+			}
+		}
+		sort.Strings(order)
+	}
+	order = append(order, noGroup)
+
+	var groups []resultGroup
+	for _, name := range order {
+		if len(buckets[name]) > 0 {
+			groups = append(groups, resultGroup{Name: name, Results: buckets[name]})
+		}
+	}
+	return groups, nil
+}
+
+// validateFacet checks that facet names a built-in facet or a top-level taxonomy entry
+func validateFacet(facet string, taxonomy *Taxonomy) error {
+	if containsFold(builtinFacets, facet) || taxonomy.isFacet(facet) {
+		return nil
+	}
+	return fmt.Errorf("unknown facet %q (expected %s)", facet, strings.Join(availableFacets(taxonomy), ", "))
+}
+
+// availableFacets lists the facets accepted by --group-by
+func availableFacets(taxonomy *Taxonomy) []string {
+	facets := append([]string(nil), builtinFacets...)
+	if taxonomy != nil {
+		facets = append(facets, taxonomy.roots...)
+	}
+	return facets
+}
+
+// groupedDocument is the json and yaml output of grouped results
+type groupedDocument struct {
+	Version int             `json:"version" yaml:"version"`
+	Groups  []groupedRecord `json:"groups" yaml:"groups"`
+}
+
+// groupedRecord is a single bucket in groupedDocument
+type groupedRecord struct {
+	Group   string         `json:"group" yaml:"group"`
+	Results []resultRecord `json:"results" yaml:"results"`
+}
+
+// writeGroupedResults writes grouped results. The Markdown formats get a
+// heading per group; json and yaml nest the results under their group. The
+// flags are only written with includeFlag.
+func writeGroupedResults(w io.Writer, groups []resultGroup, format OutputFormat, includeFlag bool) error {
+	switch format {
+	case FormatMarkdown, FormatMarkdownTable:
+		for i, group := range groups {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "## %s\n\n", group.Name)
+			if err := writeResults(w, group.Results, format, includeFlag); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON, FormatYAML:
+		doc := groupedDocument{Version: outputSchemaVersion, Groups: []groupedRecord{}}
+		for _, group := range groups {
+			record := groupedRecord{Group: group.Name}
+			for _, result := range group.Results {
+				record.Results = append(record.Results, newResultRecord(result, includeFlag))
+			}
+			doc.Groups = append(doc.Groups, record)
+		}
+
+		if format == FormatJSON {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(doc)
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("--group-by is not supported with --format %s (use markdown, markdown-table, json or yaml)", format)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func groupNames(groups []resultGroup) map[string][]string {
+	names := make(map[string][]string)
+	for _, group := range groups {
+		names[group.Name] = resultNames(group.Results)
+	}
+	return names
+}
+
+func TestGroupResults(t *testing.T) {
+	taxonomy := testTaxonomy(t)
+	challenges := testChallenges()
+
+	tests := []struct {
+		facet         string
+		expectedOrder []string
+		expected      map[string][]string
+	}{
+		{
+			facet:         "difficulty",
+			expectedOrder: []string{"beginner", "easy", "medium"},
+			expected: map[string][]string{
+				"beginner": {"SQL Injection Basics", "Social Media Investigation"},
+				"easy":     {"SQL Injection Basics", "Geolocation Challenge"},
+				"medium":   {"Social Media Investigation"},
+			},
+		},
+		{
+			// Challenges are bucketed by the child whose subtree holds their tags
+			facet:         "category",
+			expectedOrder: []string{"web-exploitation", "recon"},
+			expected: map[string][]string{
+				"web-exploitation": {"SQL Injection Basics"},
+				"recon":            {"Geolocation Challenge", "Social Media Investigation"},
+			},
+		},
+		{
+			facet:         "author",
+			expectedOrder: []string{"OSINT Team", "Web Security Team"},
+			expected: map[string][]string{
+				"OSINT Team":        {"Geolocation Challenge", "Social Media Investigation"},
+				"Web Security Team": {"SQL Injection Basics"},
+			},
+		},
+		{
+			facet:         "branch",
+			expectedOrder: []string{noGroup},
+			expected: map[string][]string{
+				noGroup: {"SQL Injection Basics", "Geolocation Challenge", "Social Media Investigation"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		groups, err := groupResults(challenges, tt.facet, taxonomy)
+		if err != nil {
+			t.Errorf("groupResults(%q) unexpected error: %v", tt.facet, err)
+			continue
+		}
+
+		var order []string
+		for _, group := range groups {
+			order = append(order, group.Name)
+		}
+		if !reflect.DeepEqual(order, tt.expectedOrder) {
+			t.Errorf("groupResults(%q) order = %v, want %v", tt.facet, order, tt.expectedOrder)
+		}
+		if got := groupNames(groups); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("groupResults(%q) = %v, want %v", tt.facet, got, tt.expected)
+		}
+	}
+
+	if _, err := groupResults(challenges, "platform", taxonomy); err == nil {
+		t.Error("Expected error for unknown facet")
+	}
+}
+
+func TestGroupResultsByTagWithUngrouped(t *testing.T) {
+	challenges := []ChallengeResult{
+		{Name: "A", Tags: []string{"web", "easy"}},
+		{Name: "B"},
+		{Name: "C", Tags: []string{"easy"}},
+	}
+
+	groups, err := groupResults(challenges, "tag", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var order []string
+	for _, group := range groups {
+		order = append(order, group.Name)
+	}
+	if expected := []string{"easy", "web", noGroup}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected groups %v with the ungrouped bucket last, got %v", expected, order)
+	}
+}
+
+func TestWriteGroupedResults(t *testing.T) {
+	groups := []resultGroup{
+		{Name: "easy", Results: []ChallengeResult{{Name: "A"}, {Name: "B"}}},
+		{Name: "hard", Results: []ChallengeResult{{Name: "C"}}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeGroupedResults(&buf, groups, FormatMarkdown, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "## easy\n\n- \"A\"\n- \"B\"\n\n## hard\n\n- \"C\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeGroupedResults(&buf, groups, FormatJSON, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var doc groupedDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Version != outputSchemaVersion || len(doc.Groups) != 2 || doc.Groups[0].Group != "easy" || len(doc.Groups[0].Results) != 2 {
+		t.Errorf("Unexpected grouped document %+v", doc)
+	}
+
+	if err := writeGroupedResults(&bytes.Buffer{}, groups, FormatCSV, true); err == nil {
+		t.Error("Expected error for a format that cannot be grouped")
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// indexFormatVersion is the version of the index file. An index written by
+// another version is discarded and rebuilt.
+const indexFormatVersion = 1
+
+func init() {
+	// The types yaml.v3 produces for the other keys of challenge.yml
+	gob.Register(map[string]interface{}{})
+	gob.Register(map[interface{}]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+}
+
+// ChallengeIndex caches what the branch loaders read from git between runs:
+// the challenge.yml files of each genre directory, keyed by the directory's
+// tree ID, and each parsed challenge.yml, keyed by its blob ID. Git object IDs
+// change whenever the content does, so entries never go stale; a branch that
+// did not change since the last run is loaded without reading any file.
+// Entries of trees that git no longer has are dropped when the index is
+// saved. The last commit times of the challenge files are kept per ref and
+// looked up again when the ref moves. The methods are safe for concurrent
+// use, and a nil index caches nothing.
+type ChallengeIndex struct {
+	Path string
+
+	mu    sync.Mutex
+	trees map[string][]TreeEntry
+	blobs map[string]indexedBlob
+	times map[string]refTimes // Keyed by full ref name
+	used  map[string]bool     // Trees listed by this run, which are known to exist
+	dirty bool                // Entries were added or dropped since the index was read
+}
+
+// refTimes are the last commit times of the challenge files on a ref, valid
+// while the ref points to Commit. Files that no commit touches have a zero
+// time, so that they are not looked up again.
+type refTimes struct {
+	Commit string
+	Times  map[string]time.Time
+}
+
+// indexedBlob is a parsed challenge.yml. Files that are not valid YAML are
+// remembered too, so that the warning can be repeated without reading them.
+type indexedBlob struct {
+	Challenge  *Challenge
+	ParseError string
+}
+
+// indexFile is the content of the index on disk
+type indexFile struct {
+	Version int
+	Trees   map[string][]TreeEntry // Paths are relative to the genre directory
+	Blobs   map[string]indexedBlob
+	Times   map[string]refTimes
+}
+
+// errParse is a parse error restored from the index
+type errParse string
+
+func (e errParse) Error() string { return string(e) }
+
+// defaultIndexPath returns the index of the repository in the current
+// directory, .git/searchall/index. Linked worktrees share the index.
+func defaultIndexPath() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--git-common-dir").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the git directory: %w", err)
+	}
+	return filepath.Join(strings.TrimSpace(string(output)), "searchall", "index"), nil
+}
+
+// openDefaultIndex opens the index of the current repository, warning and
+// returning nil when there is none so that loading continues without a cache
+func openDefaultIndex() *ChallengeIndex {
+	indexPath, err := defaultIndexPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Not using the index: %v\n", err)
+		return nil
+	}
+	return openIndex(indexPath)
+}
+
+// openIndex reads the index at indexPath. A missing, unreadable or outdated
+// index gives an empty one, which is written when Save is called.
+func openIndex(indexPath string) *ChallengeIndex {
+	index := &ChallengeIndex{
+		Path:  indexPath,
+		trees: make(map[string][]TreeEntry),
+		blobs: make(map[string]indexedBlob),
+		times: make(map[string]refTimes),
+		used:  make(map[string]bool),
+	}
+
+	file, err := os.Open(indexPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to read the index: %v\n", err)
+		}
+		return index
+	}
+	defer file.Close()
+
+	var data indexFile
+	if err := gob.NewDecoder(file).Decode(&data); err != nil || data.Version != indexFormatVersion {
+		// Rebuilt on the next Save
+		index.dirty = true
+		return index
+	}
+	if data.Trees != nil {
+		index.trees = data.Trees
+	}
+	if data.Blobs != nil {
+		index.blobs = data.Blobs
+	}
+	if data.Times != nil {
+		index.times = data.Times
+	}
+	return index
+}
+
+// listing returns the challenge.yml files under genre on branch, like
+// listChallengeEntries, from the index when the directory is unchanged.
+// Listings that fail are not cached, so a later run tries again.
+func (x *ChallengeIndex) listing(repo GitRepository, branch, genre string) ([]TreeEntry, error) {
+	if x == nil {
+		return listChallengeEntries(repo, branch, genre)
+	}
+
+	tree, err := repo.TreeID(branch, genre)
+	if err != nil {
+		// A genre missing from the branch has no challenges
+		return nil, nil
+	}
+
+	x.mu.Lock()
+	cached, ok := x.trees[tree]
+	x.used[tree] = true
+	x.mu.Unlock()
+	if ok {
+		entries := make([]TreeEntry, len(cached))
+		for i, entry := range cached {
+			entries[i] = TreeEntry{Path: path.Join(genre, entry.Path), OID: entry.OID}
+		}
+		return entries, nil
+	}
+
+	entries, err := listChallengeTree(repo, branch, genre)
+	if err != nil {
+		return nil, err
+	}
+	// The same tree can appear under another genre name, so the paths are
+	// stored relative to the genre
+	relative := make([]TreeEntry, len(entries))
+	for i, entry := range entries {
+		relative[i] = TreeEntry{Path: strings.TrimPrefix(entry.Path, genre+"/"), OID: entry.OID}
+	}
+	x.mu.Lock()
+	x.trees[tree] = relative
+	x.dirty = true
+	x.mu.Unlock()
+	return entries, nil
+}
+
+// challenge parses the challenge.yml with the given blob ID, from the index
+// when it was parsed before. Files that cannot be read are not cached.
+func (x *ChallengeIndex) challenge(repo GitRepository, oid string) parsedBlob {
+	if x != nil {
+		x.mu.Lock()
+		cached, ok := x.blobs[oid]
+		x.mu.Unlock()
+		if ok {
+			if cached.ParseError != "" {
+				return parsedBlob{parseErr: errParse(cached.ParseError)}
+			}
+			return parsedBlob{challenge: cached.Challenge}
+		}
+	}
+
+	content, err := repo.ReadBlob(oid)
+	if err != nil {
+		return parsedBlob{readErr: err}
+	}
+	var blob parsedBlob
+	var cached indexedBlob
+	var challenge Challenge
+	if err := yaml.Unmarshal(content, &challenge); err != nil {
+		blob.parseErr = err
+		cached.ParseError = err.Error()
+	} else {
+		blob.challenge = &challenge
+		cached.Challenge = &challenge
+	}
+
+	if x != nil {
+		x.mu.Lock()
+		x.blobs[oid] = cached
+		x.dirty = true
+		x.mu.Unlock()
+	}
+	return blob
+}
+
+// modTimes returns the last commit times of paths on ref, from the index
+// while the ref has not moved. Paths that no commit touches are left out.
+func (x *ChallengeIndex) modTimes(repo GitRepository, ref string, paths []string) (map[string]time.Time, error) {
+	if x == nil {
+		return repo.LastCommitTimes(ref, paths)
+	}
+	commit, err := repo.CommitID(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	x.mu.Lock()
+	cached := x.times[ref]
+	x.mu.Unlock()
+	if cached.Commit == commit {
+		times := make(map[string]time.Time, len(paths))
+		complete := true
+		for _, file := range paths {
+			modTime, ok := cached.Times[file]
+			complete = complete && ok
+			if !modTime.IsZero() {
+				times[file] = modTime
+			}
+		}
+		if complete {
+			return times, nil
+		}
+	}
+
+	times, err := repo.LastCommitTimes(ref, paths)
+	if err != nil {
+		return nil, err
+	}
+	// The files of a single genre may be looked up first, so the times
+	// already known for the commit are kept
+	entry := refTimes{Commit: commit, Times: make(map[string]time.Time)}
+	if cached.Commit == commit {
+		for file, modTime := range cached.Times {
+			entry.Times[file] = modTime
+		}
+	}
+	for _, file := range paths {
+		entry.Times[file] = times[file]
+	}
+	x.mu.Lock()
+	x.times[ref] = entry
+	x.dirty = true
+	x.mu.Unlock()
+	return times, nil
+}
+
+// Save writes the index if entries were added. The file is replaced
+// atomically, so concurrent runs never see a partial index.
+func (x *ChallengeIndex) Save() error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(x.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(x.Path), filepath.Base(x.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	data := indexFile{Version: indexFormatVersion, Trees: x.trees, Blobs: x.blobs, Times: x.times}
+	if err := gob.NewEncoder(temp).Encode(&data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+	if err := os.Rename(temp.Name(), x.Path); err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+	x.dirty = false
+	return nil
+}
+
+// prune drops the trees that are neither used by this run nor in repo any
+// more, e.g. those of branches that were deleted and garbage collected, the
+// files no remaining tree lists, and the commit times of deleted refs. It
+// only runs when the index is about to be written, so an unchanged index
+// costs no lookups.
+func (x *ChallengeIndex) prune(repo GitRepository) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.dirty {
+		return
+	}
+
+	for tree := range x.trees {
+		if x.used[tree] {
+			continue
+		}
+		// A tree ID is a tree-ish, so it resolves to itself while git has it
+		if _, err := repo.TreeID(tree, ""); err != nil {
+			delete(x.trees, tree)
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, entries := range x.trees {
+		for _, entry := range entries {
+			listed[entry.OID] = true
+		}
+	}
+	for oid := range x.blobs {
+		if !listed[oid] {
+			delete(x.blobs, oid)
+		}
+	}
+
+	if len(x.times) == 0 {
+		return
+	}
+	refs, err := repo.Refs()
+	if err != nil {
+		return
+	}
+	exists := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		exists[ref.FullName] = true
+	}
+	for ref := range x.times {
+		if !exists[ref] {
+			delete(x.times, ref)
+		}
+	}
+}
+
+// saveIndex saves the index after a load, dropping the entries repo no longer
+// has and warning when it cannot be written
+func saveIndex(repo GitRepository, index *ChallengeIndex) {
+	index.prune(repo)
+	if err := index.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// reset empties the index, so that the next Save drops every entry
+func (x *ChallengeIndex) reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.trees = make(map[string][]TreeEntry)
+	x.blobs = make(map[string]indexedBlob)
+	x.times = make(map[string]refTimes)
+	x.used = make(map[string]bool)
+	x.dirty = true
+}
+
+// indexStats describes the index for `searchall index stats`
+type indexStats struct {
+	Path       string
+	Size       int64 // Bytes on disk, 0 when the index was not written yet
+	Trees      int
+	Challenges int
+	Invalid    int // Files that are not valid YAML
+	Fresh      int // Genre directories of the current branches found in the index
+	Listings   int // Genre directories of the current branches
+}
+
+// stats counts the entries of the index, and how many genre directories of
+// the given branches it covers
+func (x *ChallengeIndex) stats(repo GitRepository, branches, genres []string) indexStats {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	stats := indexStats{Path: x.Path, Trees: len(x.trees)}
+	if info, err := os.Stat(x.Path); err == nil {
+		stats.Size = info.Size()
+	}
+	for _, blob := range x.blobs {
+		if blob.ParseError != "" {
+			stats.Invalid++
+		} else {
+			stats.Challenges++
+		}
+	}
+	for _, branch := range branches {
+		for _, genre := range genres {
+			tree, err := repo.TreeID(branch, genre)
+			if err != nil {
+				continue
+			}
+			stats.Listings++
+			if _, ok := x.trees[tree]; ok {
+				stats.Fresh++
+			}
+		}
+	}
+	return stats
+}
+
+// writeIndexStats writes the statistics as aligned "key: value" lines
+func writeIndexStats(w io.Writer, stats indexStats) {
+	fmt.Fprintf(w, "Index:       %s\n", stats.Path)
+	fmt.Fprintf(w, "Size:        %s\n", formatSize(stats.Size))
+	fmt.Fprintf(w, "Directories: %d\n", stats.Trees)
+	fmt.Fprintf(w, "Challenges:  %d (%d invalid)\n", stats.Challenges, stats.Invalid)
+	fmt.Fprintf(w, "Up to date:  %d of %d genre directories on the selected refs\n", stats.Fresh, stats.Listings)
+}
+
+// formatSize renders a byte count with a binary unit
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, prefix := float64(size)/unit, 0
+	for value >= unit && prefix < 3 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[prefix])
+}
+
+// runIndexCommand implements `searchall index rebuild|stats|clear`
+func runIndexCommand(args []string) error {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	jobs := flags.Int("jobs", 0, "Branches and files loaded in parallel by rebuild (0: one per CPU)")
+	refsFlags := addRefFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: searchall index rebuild|stats|clear [flags]")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected rebuild, stats or clear")
+	}
+	action := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	refs, err := refsFlags()
+	if err != nil {
+		return err
+	}
+
+	indexPath, err := defaultIndexPath()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "clear":
+		if err := os.Remove(indexPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove the index: %w", err)
+		}
+		// The directory only holds the index
+		_ = os.Remove(filepath.Dir(indexPath))
+		fmt.Printf("Removed %s\n", indexPath)
+		return nil
+
+	case "rebuild", "stats":
+		config, err := loadConfig("config.yaml")
+		if err != nil {
+			return fmt.Errorf("failed to load config.yaml: %w", err)
+		}
+		index := openIndex(indexPath)
+
+		if action == "stats" {
+			allRefs, err := defaultRepository.Refs()
+			if err != nil {
+				return err
+			}
+			var branches []string
+			for _, ref := range refs.filter(allRefs) {
+				branches = append(branches, ref.FullName)
+			}
+			sort.Strings(branches)
+			writeIndexStats(os.Stdout, index.stats(defaultRepository, branches, config.Genre))
+			return nil
+		}
+
+		loader, err := newMultiBranchLoader(refs, config.Branches, *jobs)
+		if err != nil {
+			return err
+		}
+		// The differing copies are parsed too when looking for conflicts,
+		// not only the ones kept
+		loader.DetectConflicts = true
+		loader.Index = index
+		index.reset()
+		ctx, stop := newInterruptContext()
+		defer stop()
+		if _, err := loader.LoadChallenges(ctx, config.Genre); err != nil {
+			return fmt.Errorf("failed to load challenges: %w", err)
+		}
+		if err := index.Save(); err != nil {
+			return err
+		}
+		stats := index.stats(defaultRepository, nil, nil)
+		fmt.Printf("Indexed %d challenge file(s) in %d directories into %s\n", stats.Challenges+stats.Invalid, stats.Trees, indexPath)
+		return nil
+	}
+	return fmt.Errorf("unknown index command %q (expected rebuild, stats or clear)", action)
+}
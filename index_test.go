@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChallengeIndexServesUnchangedBranches(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {
+			"web/a/challenge.yml": "name: A\ntags: [easy]\npoints: 100\nfiles: [public/a.txt, null]\nreleased: 2024-01-02\n",
+			"web/b/challenge.yml": "name: B\nmeta: {1: one, two: 2}\n",
+		},
+		"feat": {
+			"web/a/challenge.yml": "name: A\ntags: [easy]\npoints: 100\nfiles: [public/a.txt, null]\nreleased: 2024-01-02\n",
+			"web/b/challenge.yml": "name: B changed\n",
+		},
+	})
+	indexPath := filepath.Join(t.TempDir(), "searchall", "index")
+
+	load := func() []ChallengeResult {
+		loader := &MultiBranchLoader{CurrentBranch: "main", Git: repo, DetectConflicts: true, Index: openIndex(indexPath)}
+		results, err := loader.LoadChallenges(context.Background(), []string{"web"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return results
+	}
+
+	first := load()
+	if reads := repo.reads.Load(); reads != 3 {
+		t.Errorf("Expected 3 reads on the first run, got %d", reads)
+	}
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Fatalf("Expected the index to be written: %v", err)
+	}
+
+	repo.reads.Store(0)
+	second := load()
+	if reads := repo.reads.Load(); reads != 0 {
+		t.Errorf("Expected no reads with an up-to-date index, got %d", reads)
+	}
+	// The other keys keep the types yaml.v3 decoded them to
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same results from the index:\n%v\n%v", first, second)
+	}
+
+	// Only the changed file is read again
+	repo.branches["feat"]["web/c/challenge.yml"] = "name: C\n"
+	repo.reads.Store(0)
+	third := load()
+	if reads := repo.reads.Load(); reads != 1 {
+		t.Errorf("Expected 1 read after adding a file, got %d", reads)
+	}
+	if len(third) != 3 {
+		t.Errorf("Expected 3 challenges, got %d", len(third))
+	}
+}
+
+func TestChallengeIndexRemembersParseErrors(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/broken/challenge.yml": "name: [\n"},
+	})
+	indexPath := filepath.Join(t.TempDir(), "index")
+
+	for run := 0; run < 2; run++ {
+		index := openIndex(indexPath)
+		entries, err := index.listing(repo, "main", "web")
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("Expected 1 entry, got %v", entries)
+		}
+		blob := index.challenge(repo, entries[0].OID)
+		if blob.challenge != nil || blob.parseErr == nil {
+			t.Errorf("Run %d: expected a parse error, got %+v", run, blob)
+		}
+		if err := index.Save(); err != nil {
+			t.Fatalf("Failed to save the index: %v", err)
+		}
+	}
+	if reads := repo.reads.Load(); reads != 1 {
+		t.Errorf("Expected the broken file to be read once, got %d reads", reads)
+	}
+}
+
+func TestChallengeIndexDoesNotCacheFailedListings(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/a/challenge.yml": "name: A\n", "web/b/challenge.yml": "name: B\n"},
+	})
+	indexPath := filepath.Join(t.TempDir(), "index")
+
+	// The first listing fails once, as when a cat-file process dies
+	repo.listFailures.Store(1)
+	if _, err := openIndex(indexPath).listing(repo, "main", "web"); err == nil {
+		t.Fatal("Expected the listing error to be returned")
+	}
+
+	repo.listFailures.Store(1)
+	loader := &MultiBranchLoader{CurrentBranch: "main", Git: repo, Index: openIndex(indexPath)}
+	if challenges, err := loader.LoadChallenges(context.Background(), []string{"web"}); err != nil || len(challenges) != 0 {
+		t.Fatalf("Expected no challenges from the failed listing, got %v, %v", challenges, err)
+	}
+
+	// The failure was not saved, so the next load lists the genre again
+	loader.Index = openIndex(indexPath)
+	challenges, err := loader.LoadChallenges(context.Background(), []string{"web"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(challenges) != 2 {
+		t.Errorf("Expected 2 challenges after the failure, got %d", len(challenges))
+	}
+}
+
+func TestChallengeIndexListingKeepsGenre(t *testing.T) {
+	// The same tree under two genre names gives paths under each genre
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/a/challenge.yml": "name: A\n"},
+		"old":  {"misc/a/challenge.yml": "name: A\n"},
+	})
+	index := openIndex(filepath.Join(t.TempDir(), "index"))
+
+	web, _ := index.listing(repo, "main", "web")
+	misc, _ := index.listing(repo, "old", "misc")
+	if len(web) != 1 || web[0].Path != "web/a/challenge.yml" {
+		t.Errorf("Unexpected listing %v", web)
+	}
+	if len(misc) != 1 || misc[0].Path != "misc/a/challenge.yml" {
+		t.Errorf("Unexpected listing %v", misc)
+	}
+	if entries, err := index.listing(repo, "main", "osint"); entries != nil || err != nil {
+		t.Errorf("Expected no entries and no error for a missing genre, got %v, %v", entries, err)
+	}
+
+	var nilIndex *ChallengeIndex
+	if entries, _ := nilIndex.listing(repo, "main", "web"); !reflect.DeepEqual(entries, web) {
+		t.Errorf("Expected a nil index to list %v, got %v", web, entries)
+	}
+	if err := nilIndex.Save(); err != nil {
+		t.Errorf("Unexpected error saving a nil index: %v", err)
+	}
+}
+
+func TestChallengeIndexPrunesMissingTrees(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/a/challenge.yml": "name: A\n"},
+		"feat": {"web/a/challenge.yml": "name: A\n", "web/b/challenge.yml": "name: B\n"},
+	})
+	indexPath := filepath.Join(t.TempDir(), "index")
+
+	load := func(refs RefFilter) *ChallengeIndex {
+		loader := &MultiBranchLoader{CurrentBranch: "main", Git: repo, Refs: refs, Index: openIndex(indexPath)}
+		if _, err := loader.LoadChallenges(context.Background(), []string{"web"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return openIndex(indexPath)
+	}
+
+	if index := load(RefFilter{}); len(index.trees) != 2 || len(index.blobs) != 2 {
+		t.Fatalf("Expected 2 trees and 2 files, got %d and %d", len(index.trees), len(index.blobs))
+	}
+
+	// The old tree of main is gone; feat is not loaded but still exists
+	repo.branches["main"]["web/c/challenge.yml"] = "name: C\n"
+	if index := load(RefFilter{Include: []string{"main"}}); len(index.trees) != 2 || len(index.blobs) != 3 {
+		t.Errorf("Expected 2 trees and 3 files, got %d and %d", len(index.trees), len(index.blobs))
+	}
+
+	// Once feat is deleted, its tree and the file only it had are dropped
+	delete(repo.branches, "feat")
+	repo.branches["main"]["web/d/challenge.yml"] = "name: D\n"
+	index := load(RefFilter{})
+	if len(index.trees) != 1 || len(index.blobs) != 3 {
+		t.Errorf("Expected 1 tree and 3 files, got %d and %d", len(index.trees), len(index.blobs))
+	}
+	if _, ok := index.blobs[fakeOID("name: B\n")]; ok {
+		t.Error("Expected the file only feat had to be dropped")
+	}
+}
+
+func TestChallengeIndexCachesModTimes(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/a/challenge.yml": "name: A\n"},
+	})
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	repo.modTimes = map[string]time.Time{"main:web/a/challenge.yml": day}
+	indexPath := filepath.Join(t.TempDir(), "index")
+
+	load := func() []ChallengeResult {
+		loader := &MultiBranchLoader{CurrentBranch: "main", Git: repo, ModTimes: true, Index: openIndex(indexPath)}
+		results, err := loader.LoadChallenges(context.Background(), []string{"web"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return results
+	}
+
+	for run := 0; run < 2; run++ {
+		if results := load(); len(results) != 1 || !results[0].ModTime.Equal(day) {
+			t.Fatalf("Run %d: expected the commit time, got %v", run, results)
+		}
+	}
+	if logs := repo.logs.Load(); logs != 1 {
+		t.Errorf("Expected the times to be looked up once, got %d", logs)
+	}
+
+	// A new commit on the branch looks the times up again
+	repo.branches["main"]["web/b/challenge.yml"] = "name: B\n"
+	repo.modTimes["main:web/b/challenge.yml"] = day.AddDate(0, 0, 1)
+	if results := load(); len(results) != 2 || results[1].ModTime.IsZero() {
+		t.Errorf("Expected both commit times, got %v", results)
+	}
+	if logs := repo.logs.Load(); logs != 2 {
+		t.Errorf("Expected a second lookup after the branch moved, got %d", logs)
+	}
+}
+
+func TestOpenIndexDiscardsUnusableFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	outdated := filepath.Join(dir, "outdated")
+	var buf bytes.Buffer
+	data := indexFile{Version: indexFormatVersion + 1, Blobs: map[string]indexedBlob{"x": {ParseError: "old"}}}
+	if err := gob.NewEncoder(&buf).Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(outdated, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(dir, "corrupt")
+	if err := os.WriteFile(corrupt, []byte("not an index"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, indexPath := range []string{outdated, corrupt, filepath.Join(dir, "missing")} {
+		index := openIndex(indexPath)
+		if len(index.trees) != 0 || len(index.blobs) != 0 {
+			t.Errorf("Expected %s to give an empty index", indexPath)
+		}
+	}
+
+	// An unusable file is replaced on the next save
+	if err := openIndex(corrupt).Save(); err != nil {
+		t.Fatalf("Failed to save the index: %v", err)
+	}
+	if index := openIndex(corrupt); index.dirty {
+		t.Error("Expected the rewritten index to be readable")
+	}
+}
+
+func TestWriteIndexStats(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/a/challenge.yml": "name: A\n", "web/b/challenge.yml": "name: [\n"},
+		"feat": {"web/a/challenge.yml": "name: A changed\n"},
+	})
+	index := openIndex(filepath.Join(t.TempDir(), "index"))
+	entries, _ := index.listing(repo, "main", "web")
+	for _, entry := range entries {
+		index.challenge(repo, entry.OID)
+	}
+
+	stats := index.stats(repo, []string{"feat", "main"}, []string{"web", "osint"})
+	expected := indexStats{Path: index.Path, Trees: 1, Challenges: 1, Invalid: 1, Fresh: 1, Listings: 2}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	var buf bytes.Buffer
+	writeIndexStats(&buf, stats)
+	for _, line := range []string{"Size:        0 B", "Challenges:  1 (1 invalid)", "Up to date:  1 of 2 genre directories"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatSize(tt.size); got != tt.expected {
+			t.Errorf("formatSize(%d) = %q, expected %q", tt.size, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyKind identifies a decoded key press
+type keyKind int
+
+const (
+	keyRune keyKind = iota // printable character in key.r
+	keyCtrl                // Ctrl+letter, with the lowercase letter in key.r
+	keyAlt                 // Alt (Meta) + character in key.r
+	keyEnter
+	keyTab
+	keyBackspace
+	keyDelete // Delete-forward
+	keyEscape // lone ESC
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPgUp
+	keyPgDn
+	keyUnknown // unrecognized escape sequence
+)
+
+// key is a single decoded key press
+type key struct {
+	kind keyKind
+	r    rune
+	ctrl bool // Ctrl modifier on a special key, e.g. Ctrl+Home
+}
+
+// keyReader decodes raw terminal input into key presses
+type keyReader struct {
+	r *bufio.Reader
+}
+
+// newKeyReader wraps a raw-mode terminal input stream
+func newKeyReader(r io.Reader) *keyReader {
+	return &keyReader{r: bufio.NewReader(r)}
+}
+
+// readKey blocks until a complete key press has been read
+func (k *keyReader) readKey() (key, error) {
+	b, err := k.r.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch {
+	case b == 27:
+		return k.readEscape()
+	case b == '\r' || b == '\n':
+		return key{kind: keyEnter}, nil
+	case b == '\t':
+		return key{kind: keyTab}, nil
+	case b == 127 || b == 8:
+		return key{kind: keyBackspace}, nil
+	case b >= 1 && b <= 26:
+		return key{kind: keyCtrl, r: rune('a' + b - 1)}, nil
+	case b < 32:
+		return key{kind: keyUnknown}, nil
+	}
+
+	// Decode multi-byte UTF-8 sequences (e.g. Japanese committed by an IME)
+	r, err := readUTF8Rune(b, k.r)
+	if err != nil {
+		return key{}, err
+	}
+	return key{kind: keyRune, r: r}, nil
+}
+
+// readEscape decodes the bytes following ESC. Terminals send a whole escape
+// sequence in a single write, so an ESC with nothing buffered after it is a
+// lone Escape key press.
+func (k *keyReader) readEscape() (key, error) {
+	if k.r.Buffered() == 0 {
+		return key{kind: keyEscape}, nil
+	}
+
+	b, err := k.r.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch b {
+	case '[':
+		return k.readCSI()
+	case 'O':
+		// SS3: ESC O <final>, sent by some terminals for arrows and Home/End
+		final, err := k.r.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		return csiKey("", final), nil
+	case 27:
+		return key{kind: keyEscape}, nil
+	}
+
+	r, err := readUTF8Rune(b, k.r)
+	if err != nil {
+		return key{}, err
+	}
+	return key{kind: keyAlt, r: r}, nil
+}
+
+// readCSI reads a Control Sequence Introducer sequence of any length:
+// ESC [ <parameter bytes 0x30-0x3F>* <intermediate bytes 0x20-0x2F>* <final byte 0x40-0x7E>
+func (k *keyReader) readCSI() (key, error) {
+	var params strings.Builder
+	for {
+		b, err := k.r.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		switch {
+		case b >= 0x30 && b <= 0x3F:
+			params.WriteByte(b)
+		case b >= 0x20 && b <= 0x2F:
+			// Intermediate bytes carry no meaning for the keys we handle
+		case b >= 0x40 && b <= 0x7E:
+			return csiKey(params.String(), b), nil
+		default:
+			// Malformed sequence; give up on it
+			return key{kind: keyUnknown}, nil
+		}
+	}
+}
+
+// csiKey maps the parameters and final byte of a CSI or SS3 sequence to a key
+func csiKey(params string, final byte) key {
+	fields := strings.Split(params, ";")
+
+	// xterm encodes modifiers as a second parameter: 1 + (shift|alt<<1|ctrl<<2)
+	ctrl := false
+	if len(fields) >= 2 {
+		if mod := fields[1]; mod == "5" || mod == "6" || mod == "7" || mod == "8" {
+			ctrl = true
+		}
+	}
+
+	switch final {
+	case 'A':
+		return key{kind: keyUp, ctrl: ctrl}
+	case 'B':
+		return key{kind: keyDown, ctrl: ctrl}
+	case 'C':
+		return key{kind: keyRight, ctrl: ctrl}
+	case 'D':
+		return key{kind: keyLeft, ctrl: ctrl}
+	case 'H':
+		return key{kind: keyHome, ctrl: ctrl}
+	case 'F':
+		return key{kind: keyEnd, ctrl: ctrl}
+	case '~':
+		switch fields[0] {
+		case "1", "7":
+			return key{kind: keyHome, ctrl: ctrl}
+		case "3":
+			return key{kind: keyDelete, ctrl: ctrl}
+		case "4", "8":
+			return key{kind: keyEnd, ctrl: ctrl}
+		case "5":
+			return key{kind: keyPgUp, ctrl: ctrl}
+		case "6":
+			return key{kind: keyPgDn, ctrl: ctrl}
+		}
+	}
+	return key{kind: keyUnknown}
+}
+
+// utf8SequenceLength returns the length of the UTF-8 sequence introduced by
+// the given leading byte, or 0 if the byte cannot start a sequence
+func utf8SequenceLength(first byte) int {
+	switch {
+	case first < utf8.RuneSelf:
+		return 1
+	case first&0xE0 == 0xC0:
+		return 2
+	case first&0xF0 == 0xE0:
+		return 3
+	case first&0xF8 == 0xF0:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// readUTF8Rune completes a UTF-8 sequence whose leading byte has already been
+// read. Invalid or truncated sequences decode to utf8.RuneError.
+func readUTF8Rune(first byte, r io.Reader) (rune, error) {
+	length := utf8SequenceLength(first)
+	if length == 0 {
+		return utf8.RuneError, nil
+	}
+	if length == 1 {
+		return rune(first), nil
+	}
+
+	buf := make([]byte, length)
+	buf[0] = first
+	if _, err := io.ReadFull(r, buf[1:]); err != nil {
+		return utf8.RuneError, err
+	}
+
+	decoded, _ := utf8.DecodeRune(buf)
+	return decoded, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReadUTF8Rune(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected rune
+	}{
+		{name: "ASCII", input: "a", expected: 'a'},
+		{name: "Two bytes", input: "é", expected: 'é'},
+		{name: "Three bytes", input: "ジ", expected: 'ジ'},
+		{name: "Four bytes", input: "🔍", expected: '🔍'},
+		{name: "Stray continuation byte", input: "\x80", expected: utf8.RuneError},
+		{name: "Invalid continuation", input: "\xe3\x41\x41", expected: utf8.RuneError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.input)
+			got, err := readUTF8Rune(data[0], bytes.NewReader(data[1:]))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %U, got %U", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadUTF8RuneTruncated(t *testing.T) {
+	data := []byte("ジ")
+	if _, err := readUTF8Rune(data[0], bytes.NewReader(data[1:2])); err == nil {
+		t.Error("Expected error for truncated sequence")
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected key
+	}{
+		{name: "Printable", input: "a", expected: key{kind: keyRune, r: 'a'}},
+		{name: "Multibyte", input: "位", expected: key{kind: keyRune, r: '位'}},
+		{name: "Enter", input: "\r", expected: key{kind: keyEnter}},
+		{name: "Tab", input: "\t", expected: key{kind: keyTab}},
+		{name: "Backspace", input: "\x7f", expected: key{kind: keyBackspace}},
+		{name: "Ctrl-W", input: "\x17", expected: key{kind: keyCtrl, r: 'w'}},
+		{name: "Lone escape", input: "\x1b", expected: key{kind: keyEscape}},
+		{name: "Up", input: "\x1b[A", expected: key{kind: keyUp}},
+		{name: "SS3 Home", input: "\x1bOH", expected: key{kind: keyHome}},
+		{name: "CSI Home", input: "\x1b[H", expected: key{kind: keyHome}},
+		{name: "VT Home", input: "\x1b[1~", expected: key{kind: keyHome}},
+		{name: "VT End", input: "\x1b[4~", expected: key{kind: keyEnd}},
+		{name: "Delete", input: "\x1b[3~", expected: key{kind: keyDelete}},
+		{name: "PgUp", input: "\x1b[5~", expected: key{kind: keyPgUp}},
+		{name: "PgDn", input: "\x1b[6~", expected: key{kind: keyPgDn}},
+		{name: "Ctrl-Home", input: "\x1b[1;5H", expected: key{kind: keyHome, ctrl: true}},
+		{name: "Ctrl-Right", input: "\x1b[1;5C", expected: key{kind: keyRight, ctrl: true}},
+		{name: "Ctrl-Delete", input: "\x1b[3;5~", expected: key{kind: keyDelete, ctrl: true}},
+		{name: "Unknown CSI", input: "\x1b[200~", expected: key{kind: keyUnknown}},
+		{name: "Alt-B", input: "\x1bb", expected: key{kind: keyAlt, r: 'b'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newKeyReader(bytes.NewReader([]byte(tt.input)))
+			got, err := keys.readKey()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadKeySequence(t *testing.T) {
+	// Variable-length sequences must not swallow the following key
+	keys := newKeyReader(bytes.NewReader([]byte("\x1b[5~x\x1b[1;5Hy")))
+
+	expected := []key{
+		{kind: keyPgUp},
+		{kind: keyRune, r: 'x'},
+		{kind: keyHome, ctrl: true},
+		{kind: keyRune, r: 'y'},
+	}
+	for i, want := range expected {
+		got, err := keys.readKey()
+		if err != nil {
+			t.Fatalf("Key %d: unexpected error: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("Key %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
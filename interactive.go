@@ -0,0 +1,670 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// searchState holds the state of the interactive search UI
+type searchState struct {
+	input     []rune
+	cursorPos int
+	mode      MatchMode // Match mode of tag terms: the configured one until Ctrl-T toggles fuzzy matching
+	query     *Query    // Query that produced results, used for highlighting
+	results   []ChallengeResult
+	total     int   // Number of loaded challenges, for the "N of M" counter
+	selected  int   // Index of the highlighted result
+	offset    int   // Index of the first result shown in the viewport
+	err       error // Parse error of the current input, if any
+	width     int   // Terminal size; zero means unknown
+	height    int
+	preview   bool                         // Show the preview pane
+	previews  map[string]*challengePreview // Cache keyed by previewKey
+	keys      keyBindings                  // Action bindings, listed in the help line
+	message   string                       // Shown in the status line until the next key press
+	marked    []ChallengeResult            // Challenges picked with Tab, in the order they were picked
+	quitting  bool                         // Ctrl-C was pressed once with challenges picked
+}
+
+// minPreviewWidth is the narrowest terminal that still gets a preview pane
+const minPreviewWidth = 60
+
+// Fallback terminal size when it cannot be queried
+const (
+	defaultTermWidth  = 80
+	defaultTermHeight = 24
+)
+
+// uiChromeLines is the number of screen lines used by everything but the
+// result list: the input line, the status line and the help line
+const uiChromeLines = 3
+
+// termSize returns the terminal size, falling back to defaults when unknown
+func (s *searchState) termSize() (int, int) {
+	width, height := s.width, s.height
+	if width <= 0 {
+		width = defaultTermWidth
+	}
+	if height <= 0 {
+		height = defaultTermHeight
+	}
+	return width, height
+}
+
+// pageSize is the number of result rows that fit on the screen. PgUp/PgDn
+// move the selection by this many rows.
+func (s *searchState) pageSize() int {
+	_, height := s.termSize()
+	return max(1, height-uiChromeLines)
+}
+
+// selectedPreview returns the preview of the highlighted challenge, loading
+// and caching it on first use
+func (s *searchState) selectedPreview() *challengePreview {
+	selected, ok := s.selectedResult()
+	if !ok {
+		return nil
+	}
+
+	if s.previews == nil {
+		s.previews = make(map[string]*challengePreview)
+	}
+	key := previewKey(selected)
+	if preview, ok := s.previews[key]; ok {
+		return preview
+	}
+
+	preview := loadChallengePreview(selected)
+	s.previews[key] = preview
+	return preview
+}
+
+// scrollToSelection adjusts the viewport so that the selected row is visible
+func (s *searchState) scrollToSelection() {
+	page := s.pageSize()
+	if s.selected < s.offset {
+		s.offset = s.selected
+	}
+	if s.selected >= s.offset+page {
+		s.offset = s.selected - page + 1
+	}
+	s.offset = max(0, min(s.offset, len(s.results)-page))
+}
+
+// update re-runs the search for the current input. When the input does not
+// parse (e.g. an unclosed parenthesis while typing), the previous results are
+// kept so that the list does not blank out.
+func (s *searchState) update(allChallenges []ChallengeResult, opts SearchOptions) {
+	queryOpts := opts.Query
+	queryOpts.DefaultMode = s.mode
+
+	results, query, err := filterChallengesByInput(allChallenges, string(s.input), queryOpts)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	sortResults(results, opts.Sort)
+	s.results = results
+	s.query = query
+	s.total = len(allChallenges)
+	s.selected = 0
+	s.offset = 0
+	s.err = nil
+}
+
+// toggleFuzzy switches tag terms between the configured match mode and fuzzy
+// matching, or substring matching when fuzzy is the configured mode
+func (s *searchState) toggleFuzzy(configured MatchMode) {
+	switch {
+	case s.mode != configured:
+		s.mode = configured
+	case configured == MatchFuzzy:
+		s.mode = MatchSubstring
+	default:
+		s.mode = MatchFuzzy
+	}
+}
+
+// moveSelection moves the highlighted row by delta, clamped to the result list
+func (s *searchState) moveSelection(delta int) {
+	s.selected = max(0, min(len(s.results)-1, s.selected+delta))
+}
+
+// isMarked reports whether a challenge has been picked with Tab. Marks are
+// kept across searches, so challenges are compared by branch and path.
+func (s *searchState) isMarked(result ChallengeResult) bool {
+	key := previewKey(result)
+	for _, marked := range s.marked {
+		if previewKey(marked) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleMark picks or unpicks the highlighted challenge and moves the
+// highlight to the next row, so that consecutive rows can be picked quickly
+func (s *searchState) toggleMark() {
+	selected, ok := s.selectedResult()
+	if !ok {
+		return
+	}
+
+	key := previewKey(selected)
+	for i, marked := range s.marked {
+		if previewKey(marked) == key {
+			s.marked = append(s.marked[:i], s.marked[i+1:]...)
+			s.moveSelection(1)
+			return
+		}
+	}
+	s.marked = append(s.marked, selected)
+	s.moveSelection(1)
+}
+
+// quit reports whether Ctrl-C ends the session. Challenges picked with Tab
+// are only output by the select key, so with some picked the first Ctrl-C
+// warns that they would be discarded and the second one quits.
+func (s *searchState) quit() bool {
+	if len(s.marked) == 0 || s.quitting {
+		return true
+	}
+	s.quitting = true
+	s.message = fmt.Sprintf("%d selected challenge(s) will be discarded: press Ctrl+C again to quit", len(s.marked))
+	return false
+}
+
+// selectedResult returns the highlighted challenge, if any
+func (s *searchState) selectedResult() (ChallengeResult, bool) {
+	if s.selected < 0 || s.selected >= len(s.results) {
+		return ChallengeResult{}, false
+	}
+	return s.results[s.selected], true
+}
+
+// openTerminalOutput returns the file the UI is drawn on: stdout, or the
+// controlling terminal when stdout is redirected so that action output can be
+// captured by a shell wrapper
+func openTerminalOutput() (*os.File, func(), error) {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		return os.Stdout, func() {}, nil
+	}
+	tty, err := os.OpenFile(controllingTerminal, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open terminal: %w", err)
+	}
+	return tty, func() { _ = tty.Close() }, nil
+}
+
+// interactiveSearch provides real-time interactive search
+func interactiveSearch(allChallenges []ChallengeResult, opts SearchOptions) error {
+	tty, closeTTY, err := openTerminalOutput()
+	if err != nil {
+		return err
+	}
+	defer closeTTY()
+
+	// Save the original terminal state
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set raw mode: %w", err)
+	}
+	restored := false
+	restore := func() {
+		if !restored {
+			_ = term.Restore(int(os.Stdin.Fd()), oldState)
+			restored = true
+		}
+	}
+	defer restore()
+
+	// Clear screen and hide cursor
+	clearScreen(tty)
+	defer fmt.Fprint(tty, "\033[?25h") // Show cursor on exit
+
+	// Keys are read from a separate handle on the terminal, which is closed
+	// when the UI ends so that the reader stops instead of taking input
+	// meant for an editor
+	keyInput, err := os.Open(controllingTerminalInput)
+	if err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	done := make(chan struct{})
+	stopped := false
+	stopKeys := func() {
+		if !stopped {
+			close(done)
+			_ = keyInput.Close()
+			stopped = true
+		}
+	}
+	defer stopKeys()
+
+	// leave clears the UI and gives the terminal back, e.g. before an editor starts
+	leave := func() {
+		stopKeys()
+		clearScreen(tty)
+		fmt.Fprint(tty, "\033[?25h") // Show cursor
+		restore()
+	}
+
+	keys := opts.Keys
+	if keys == nil {
+		keys, _ = newKeyBindings(nil)
+	}
+
+	// Start in the configured mode so that a query finds the same challenges
+	// as on the command line
+	state := &searchState{mode: opts.Query.DefaultMode, preview: true, keys: keys}
+	redraw := func() {
+		if width, height, err := term.GetSize(int(tty.Fd())); err == nil {
+			state.width, state.height = width, height
+		}
+		state.scrollToSelection()
+
+		// Render the whole frame at once to avoid flicker
+		var frame bytes.Buffer
+		frame.WriteString("\033[2J\033[H\033[?25l")
+		displaySearchUIWithCursor(&frame, state)
+		_, _ = tty.Write(frame.Bytes())
+	}
+	refresh := func() {
+		state.update(allChallenges, opts)
+		redraw()
+	}
+
+	// Display initial state
+	refresh()
+
+	// Read keys in the background so that terminal resizes can be handled
+	// while waiting for input
+	keyEvents := make(chan key)
+	keyErrors := make(chan error, 1)
+	go func() {
+		keys := newKeyReader(keyInput)
+		for {
+			k, err := keys.readKey()
+			if err != nil {
+				keyErrors <- err
+				return
+			}
+			select {
+			case keyEvents <- k:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	defer stopResize(resize)
+
+	for {
+		var k key
+		select {
+		case <-resize:
+			redraw()
+			continue
+		case err := <-keyErrors:
+			return fmt.Errorf("failed to read input: %w", err)
+		case k = <-keyEvents:
+		}
+
+		before := string(state.input)
+		state.message = ""
+		// Any other key cancels a pending quit
+		if k != (key{kind: keyCtrl, r: 'c'}) {
+			state.quitting = false
+		}
+
+		// Configured action keys; parseKeySpec keeps them apart from the built-in keys
+		if action, ok := keys[k]; ok {
+			// With challenges picked by Tab, select emits all of them
+			if action == ActionSelect && len(state.marked) > 0 {
+				leave()
+				return outputResults(os.Stdout, state.marked, opts)
+			}
+
+			selected, ok := state.selectedResult()
+			if !ok {
+				continue
+			}
+			if action.exits() {
+				leave()
+				return runAction(action, selected, os.Stdout, tty)
+			}
+
+			value := selected.FilePath
+			if action == ActionCopyName {
+				value = selected.Name
+			}
+			fmt.Fprint(tty, osc52(value))
+			state.message = "Copied: " + value
+			redraw()
+			continue
+		}
+
+		switch k.kind {
+		case keyCtrl:
+			switch k.r {
+			case 'c': // Quit
+				if !state.quit() {
+					break
+				}
+				clearScreen(tty)
+				fmt.Fprint(tty, "\033[?25h") // Show cursor
+				fmt.Fprintln(tty, "Goodbye!")
+				return nil
+			case 't': // Toggle fuzzy matching
+				state.toggleFuzzy(opts.Query.DefaultMode)
+				refresh()
+				continue
+			case 'p': // Toggle the preview pane
+				state.preview = !state.preview
+			case 'a':
+				state.moveHome()
+			case 'e':
+				state.moveEnd()
+			case 'b':
+				state.moveLeft()
+			case 'f':
+				state.moveRight()
+			case 'd':
+				state.deleteForward()
+			case 'w':
+				state.deleteWordBackward()
+			case 'u':
+				state.killToStart()
+			case 'k':
+				state.killToEnd()
+			case 'l': // Redraw below
+			}
+		case keyAlt:
+			switch k.r {
+			case 'b':
+				state.moveWordBackward()
+			case 'f':
+				state.moveWordForward()
+			case '<':
+				state.selected = 0
+			case '>':
+				state.moveSelection(len(state.results))
+			}
+		case keyLeft:
+			if k.ctrl {
+				state.moveWordBackward()
+			} else {
+				state.moveLeft()
+			}
+		case keyRight:
+			if k.ctrl {
+				state.moveWordForward()
+			} else {
+				state.moveRight()
+			}
+		case keyHome:
+			if k.ctrl {
+				state.selected = 0
+			} else {
+				state.moveHome()
+			}
+		case keyEnd:
+			if k.ctrl {
+				state.moveSelection(len(state.results))
+			} else {
+				state.moveEnd()
+			}
+		case keyTab:
+			state.toggleMark()
+		case keyUp:
+			state.moveSelection(-1)
+		case keyDown:
+			state.moveSelection(1)
+		case keyPgUp:
+			state.moveSelection(-state.pageSize())
+		case keyPgDn:
+			state.moveSelection(state.pageSize())
+		case keyBackspace:
+			state.deleteBackward()
+		case keyDelete:
+			state.deleteForward()
+		case keyRune:
+			// Add printable characters at cursor position
+			if k.r != utf8.RuneError && unicode.IsPrint(k.r) {
+				state.insertRune(k.r)
+			}
+		default:
+			continue
+		}
+
+		// Re-run the search only when the query text changed, so that
+		// cursor movement keeps the highlighted row
+		if string(state.input) != before {
+			refresh()
+		} else {
+			redraw()
+		}
+	}
+}
+
+// clearScreen clears the entire screen and moves cursor to top-left
+func clearScreen(w io.Writer) {
+	fmt.Fprint(w, "\033[2J")   // Clear entire screen
+	fmt.Fprint(w, "\033[H")    // Move cursor to home position (1,1)
+	fmt.Fprint(w, "\033[?25l") // Hide cursor
+}
+
+// displaySearchUIWithCursor renders the search interface into w, fitted to
+// the terminal size recorded in state: the input line, a status line with the
+// "N of M" counter, as many results as fit starting at state.offset, and a
+// help line. Lines longer than the terminal width are truncated.
+func displaySearchUIWithCursor(w io.Writer, state *searchState) {
+	width, _ := state.termSize()
+	input := state.input
+	cursorPos := min(state.cursorPos, len(input))
+
+	prompt := fmt.Sprintf("input [%s]: ", state.mode)
+
+	// Display input line with cursor visualization. The input is sliced by
+	// rune so that multi-byte characters are never split, and scrolled
+	// horizontally so that the cursor stays on screen.
+	start := 0
+	for start < cursorPos && stringWidth(prompt)+stringWidth(string(input[start:cursorPos]))+2 > width {
+		start++
+	}
+	before := string(input[start:cursorPos])
+	if start > 0 && start < cursorPos {
+		before = "…" + string(input[start+1:cursorPos])
+	}
+	if cursorPos >= len(input) {
+		fmt.Fprintf(w, "%s%s█\r\n", prompt, before)
+	} else {
+		at := string(input[cursorPos])
+		after := truncateToWidth(string(input[cursorPos+1:]), width-stringWidth(prompt+before+at))
+		fmt.Fprintf(w, "%s%s\033[7m%s\033[0m%s\r\n", prompt, before, at, after)
+	}
+
+	// Status line: action message, parse error or match counter
+	challenges := state.results
+	if state.message != "" {
+		fmt.Fprintf(w, "%s\r\n", truncateToWidth(state.message, width))
+	} else if state.err != nil {
+		fmt.Fprintf(w, "%s\r\n", truncateToWidth(fmt.Sprintf("Query error: %v", state.err), width))
+	} else {
+		status := fmt.Sprintf("  %d of %d", len(challenges), state.total)
+		if len(state.marked) > 0 {
+			status += fmt.Sprintf(" (%d selected)", len(state.marked))
+		}
+		fmt.Fprintf(w, "\033[2m%s\033[0m\r\n", truncateToWidth(status, width))
+	}
+
+	// Split the screen between the result list and the preview pane
+	listWidth := width
+	var preview []string
+	page := state.pageSize()
+	if state.preview && width >= minPreviewWidth {
+		listWidth = width / 2
+		if selected, ok := state.selectedResult(); ok {
+			preview = previewLines(selected, state.selectedPreview(), width-listWidth-3)
+		}
+	}
+
+	// Display the visible window of results
+	var list []string
+	if len(challenges) == 0 {
+		list = append(list, "No challenges found")
+	}
+	end := min(len(challenges), state.offset+page)
+	for i := state.offset; i < end; i++ {
+		challenge := challenges[i]
+		name := highlightMatches(challenge.Name, state.query.Highlight("name", challenge.Name))
+		tags := make([]string, len(challenge.Tags))
+		for j, tag := range challenge.Tags {
+			tags[j] = highlightMatches(tag, state.query.Highlight("tag", tag))
+		}
+
+		// The highlighted row gets a '>' marker and reverse video; rows
+		// picked with Tab get a '*' marker
+		marker, style, reset := "-", "", ""
+		if i == state.selected {
+			marker, style, reset = ">", "\033[7m", "\033[0m"
+		}
+		if state.isMarked(challenge) {
+			marker = "*"
+		}
+
+		var line string
+		if challenge.BranchName != "" {
+			line = fmt.Sprintf("%s [%s] %s (tags: %s)",
+				marker,
+				challenge.BranchName,
+				name,
+				strings.Join(tags, ", "))
+		} else {
+			line = fmt.Sprintf("%s %s (tags: %s)",
+				marker,
+				name,
+				strings.Join(tags, ", "))
+		}
+		list = append(list, style+truncateToWidth(line, listWidth)+reset)
+	}
+
+	// Fill every row so that the help line stays at the bottom of the screen
+	for row := 0; row < page; row++ {
+		var line string
+		if row < len(list) {
+			line = list[row]
+		}
+		if listWidth < width {
+			var side string
+			if row < len(preview) {
+				side = preview[row]
+			}
+			line += strings.Repeat(" ", max(0, listWidth-visibleWidth(line))) + " \033[2m│\033[0m " + side
+		}
+		fmt.Fprintf(w, "%s\r\n", line)
+	}
+	help := "Up/Down/PgUp/PgDn/Ctrl+Home/Ctrl+End: move  Ctrl+T: toggle fuzzy  Ctrl+P: toggle preview  Tab: mark  " + state.keys.help() + "  Ctrl+C: quit"
+	fmt.Fprintf(w, "\033[2m%s\033[0m", truncateToWidth(help, width))
+
+	// Park the (hidden) terminal cursor at the input position, measured in
+	// display columns, so that IME composition windows open where the user types
+	fmt.Fprintf(w, "\033[1;%dH", stringWidth(prompt)+stringWidth(before)+1)
+}
+
+// truncateToWidth shortens s to at most width display columns, ending it with
+// "…" when something was cut. ANSI escape sequences in s are copied through
+// without being counted, and a highlight that was cut open is closed.
+func truncateToWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if visibleWidth(s) <= width {
+		return s
+	}
+
+	var sb strings.Builder
+	used := 0
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\033' {
+			// Copy the whole escape sequence: ESC [ ... <final byte>
+			j := i + 1
+			for j < len(runes) && (j == i+1 || runes[j] < 0x40 || runes[j] > 0x7E) {
+				j++
+			}
+			sb.WriteString(string(runes[i:min(j+1, len(runes))]))
+			i = j
+			continue
+		}
+		rw := runeWidth(runes[i])
+		if used+rw > width-1 {
+			break
+		}
+		sb.WriteRune(runes[i])
+		used += rw
+	}
+	sb.WriteString("…\033[22;39m")
+	return sb.String()
+}
+
+// visibleWidth returns the display width of s ignoring ANSI escape sequences
+func visibleWidth(s string) int {
+	width := 0
+	inEscape := false
+	for i, r := range s {
+		switch {
+		case r == '\033':
+			inEscape = true
+		case inEscape:
+			// The first byte after ESC is '[', the sequence ends at a final byte
+			if r >= 0x40 && r <= 0x7E && s[i-1] != '\033' {
+				inEscape = false
+			}
+		default:
+			width += runeWidth(r)
+		}
+	}
+	return width
+}
+
+// highlightMatches renders the runes at the given positions in bold yellow.
+// Only the foreground color and intensity are reset afterwards, so the
+// highlight can be nested inside other styles.
+func highlightMatches(s string, positions []int) string {
+	if len(positions) == 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	next := 0
+	inMatch := false
+	for i, r := range []rune(s) {
+		matched := next < len(positions) && positions[next] == i
+		if matched {
+			next++
+		}
+		if matched != inMatch {
+			if matched {
+				sb.WriteString("\033[1;33m")
+			} else {
+				sb.WriteString("\033[22;39m")
+			}
+			inMatch = matched
+		}
+		sb.WriteRune(r)
+	}
+	if inMatch {
+		sb.WriteString("\033[22;39m")
+	}
+	return sb.String()
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSearchStateUpdateFuzzy(t *testing.T) {
+	state := &searchState{input: []rune("geolocaton"), mode: MatchFuzzy}
+	state.update(testChallenges(), SearchOptions{})
+
+	if len(state.results) != 1 || state.results[0].Name != "Geolocation Challenge" {
+		t.Errorf("Expected fuzzy search to find 'Geolocation Challenge', got %v", resultNames(state.results))
+	}
+
+	state.toggleFuzzy(MatchSubstring)
+	state.update(testChallenges(), SearchOptions{})
+	if len(state.results) != 0 {
+		t.Errorf("Expected substring search to find nothing, got %v", resultNames(state.results))
+	}
+}
+
+func TestSearchStateToggleFuzzy(t *testing.T) {
+	tests := []struct {
+		configured MatchMode
+		toggled    MatchMode
+	}{
+		{MatchSubstring, MatchFuzzy},
+		{MatchExact, MatchFuzzy},
+		{MatchFuzzy, MatchSubstring},
+	}
+
+	for _, tt := range tests {
+		// The interactive mode starts in the configured mode
+		state := &searchState{mode: tt.configured}
+		state.toggleFuzzy(tt.configured)
+		if state.mode != tt.toggled {
+			t.Errorf("%s: expected Ctrl-T to switch to %s, got %s", tt.configured, tt.toggled, state.mode)
+		}
+		state.toggleFuzzy(tt.configured)
+		if state.mode != tt.configured {
+			t.Errorf("%s: expected Ctrl-T to switch back, got %s", tt.configured, state.mode)
+		}
+	}
+}
+
+func TestSearchStateUpdateJapanese(t *testing.T) {
+	challenges := append(testChallenges(), ChallengeResult{Name: "画像の撮影場所", Tags: []string{"位置情報", "画像"}})
+
+	state := &searchState{input: []rune("位置"), mode: MatchFuzzy}
+	state.update(challenges, SearchOptions{})
+	if len(state.results) != 1 || state.results[0].Name != "画像の撮影場所" {
+		t.Fatalf("Expected to find the Japanese challenge, got %v", resultNames(state.results))
+	}
+
+	if got := state.query.Highlight("tag", "位置情報"); len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("Expected the first two runes to be highlighted, got %v", got)
+	}
+}
+
+func TestSearchStateUpdateKeepsResultsOnError(t *testing.T) {
+	state := &searchState{input: []rune("medium")}
+	state.update(testChallenges(), SearchOptions{})
+	if len(state.results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(state.results))
+	}
+
+	state.input = []rune("medium (")
+	state.update(testChallenges(), SearchOptions{})
+	if state.err == nil {
+		t.Error("Expected parse error")
+	}
+	if len(state.results) != 1 {
+		t.Errorf("Expected previous results to be kept, got %d", len(state.results))
+	}
+}
+
+func TestSearchStateSelection(t *testing.T) {
+	state := &searchState{}
+	state.update(testChallenges(), SearchOptions{})
+
+	if selected, ok := state.selectedResult(); !ok || selected.Name != "SQL Injection Basics" {
+		t.Errorf("Expected first result to be selected initially, got %v", selected.Name)
+	}
+
+	state.moveSelection(1)
+	if selected, _ := state.selectedResult(); selected.Name != "Geolocation Challenge" {
+		t.Errorf("Expected second result after moving down, got %v", selected.Name)
+	}
+
+	state.moveSelection(state.pageSize())
+	if state.selected != 2 {
+		t.Errorf("Expected selection to clamp to the last row, got %d", state.selected)
+	}
+
+	state.moveSelection(-state.pageSize())
+	if state.selected != 0 {
+		t.Errorf("Expected selection to clamp to the first row, got %d", state.selected)
+	}
+
+	// A new search resets the selection to the best match
+	state.moveSelection(2)
+	state.input = []rune("medium")
+	state.update(testChallenges(), SearchOptions{})
+	if state.selected != 0 {
+		t.Errorf("Expected selection to reset after a new search, got %d", state.selected)
+	}
+
+	state.input = []rune("nomatch")
+	state.update(testChallenges(), SearchOptions{})
+	if _, ok := state.selectedResult(); ok {
+		t.Error("Expected no selection without results")
+	}
+}
+
+func TestSearchStateToggleMark(t *testing.T) {
+	state := &searchState{}
+	state.update(manyChallenges(5), SearchOptions{})
+
+	state.toggleMark()
+	state.moveSelection(1)
+	state.toggleMark()
+	if names := resultNames(state.marked); len(names) != 2 || names[0] != "Challenge 000" || names[1] != "Challenge 002" {
+		t.Fatalf("Expected challenges 000 and 002 to be marked, got %v", names)
+	}
+	if state.selected != 3 {
+		t.Errorf("Expected the highlight to advance after marking, got row %d", state.selected)
+	}
+
+	// Marks survive a new search
+	state.input = []rune("name:\"Challenge 002\"")
+	state.update(manyChallenges(5), SearchOptions{})
+	if len(state.results) != 1 || !state.isMarked(state.results[0]) {
+		t.Fatalf("Expected the mark to be kept across searches, got %v", resultNames(state.results))
+	}
+
+	// Toggling a marked row unmarks it
+	state.toggleMark()
+	if names := resultNames(state.marked); len(names) != 1 || names[0] != "Challenge 000" {
+		t.Errorf("Expected only challenge 000 to stay marked, got %v", names)
+	}
+}
+
+func TestSearchStateQuit(t *testing.T) {
+	state := &searchState{}
+	if !state.quit() {
+		t.Error("Expected Ctrl-C to quit without selected challenges")
+	}
+
+	// With selected challenges the first Ctrl-C only warns
+	state = &searchState{marked: testChallenges()[:2]}
+	if state.quit() {
+		t.Error("Expected the first Ctrl-C to ask for confirmation")
+	}
+	if !strings.Contains(state.message, "2 selected challenge(s) will be discarded") {
+		t.Errorf("Expected a warning, got %q", state.message)
+	}
+	if !state.quit() {
+		t.Error("Expected the second Ctrl-C to quit")
+	}
+}
+
+func TestDisplaySearchUIWithCursorMarks(t *testing.T) {
+	state := &searchState{width: 40, height: 8}
+	state.update(manyChallenges(3), SearchOptions{})
+	state.toggleMark()
+
+	var buf bytes.Buffer
+	displaySearchUIWithCursor(&buf, state)
+	lines := strings.Split(buf.String(), "\r\n")
+
+	if !strings.Contains(lines[1], "3 of 3 (1 selected)") {
+		t.Errorf("Expected selection count in status line, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "* Challenge 000") {
+		t.Errorf("Expected marked row to have a '*' marker, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "> Challenge 001") {
+		t.Errorf("Expected highlighted row after the marked one, got %q", lines[3])
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	got := highlightMatches("web", []int{0, 2})
+	expected := "\033[1;33mw\033[22;39me\033[1;33mb\033[22;39m"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = highlightMatches("osint", []int{1, 2})
+	expected = "o\033[1;33msi\033[22;39mnt"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got := highlightMatches("web", nil); got != "web" {
+		t.Errorf("Expected unchanged string, got %q", got)
+	}
+}
+
+func manyChallenges(n int) []ChallengeResult {
+	challenges := make([]ChallengeResult, n)
+	for i := range challenges {
+		challenges[i] = ChallengeResult{
+			Name:     fmt.Sprintf("Challenge %03d", i),
+			Tags:     []string{"web"},
+			FilePath: fmt.Sprintf("web/chall_%03d/challenge.yml", i),
+		}
+	}
+	return challenges
+}
+
+func TestSearchStateScrolling(t *testing.T) {
+	state := &searchState{width: 80, height: 10}
+	state.update(manyChallenges(50), SearchOptions{})
+
+	page := state.pageSize()
+	if page != 10-uiChromeLines {
+		t.Fatalf("Expected page size %d, got %d", 10-uiChromeLines, page)
+	}
+
+	state.moveSelection(page)
+	state.scrollToSelection()
+	if state.offset != 1 {
+		t.Errorf("Expected viewport to scroll by one row, got offset %d", state.offset)
+	}
+
+	state.moveSelection(100)
+	state.scrollToSelection()
+	if state.offset != 50-page {
+		t.Errorf("Expected viewport at the end, got offset %d", state.offset)
+	}
+
+	state.moveSelection(-100)
+	state.scrollToSelection()
+	if state.offset != 0 {
+		t.Errorf("Expected viewport at the top, got offset %d", state.offset)
+	}
+}
+
+func TestDisplaySearchUIWithCursorViewport(t *testing.T) {
+	state := &searchState{width: 40, height: 8}
+	state.update(manyChallenges(20), SearchOptions{})
+	state.moveSelection(10)
+	state.scrollToSelection()
+
+	var buf bytes.Buffer
+	displaySearchUIWithCursor(&buf, state)
+	lines := strings.Split(buf.String(), "\r\n")
+
+	if len(lines) != 8 {
+		t.Fatalf("Expected output to fill exactly 8 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[1], "20 of 20") {
+		t.Errorf("Expected counter in status line, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "Challenge 006") {
+		t.Errorf("Expected first visible row to be 'Challenge 006', got %q", lines[2])
+	}
+	if !strings.Contains(lines[6], "> Challenge 010") {
+		t.Errorf("Expected selected row at the bottom of the viewport, got %q", lines[6])
+	}
+	for i, line := range lines {
+		if w := visibleWidth(line); w > 40 {
+			t.Errorf("Line %d exceeds terminal width (%d columns): %q", i, w, line)
+		}
+	}
+}
+
+func TestDisplaySearchUIWithCursorNarrow(t *testing.T) {
+	// The prompt alone is wider than some of these terminals, so the input
+	// scrolls all the way to the cursor
+	for _, input := range []string{"easy AND osint", "位置情報 OR 地理"} {
+		for width := 1; width <= 30; width++ {
+			for _, cursor := range []int{0, 2, len([]rune(input))} {
+				state := &searchState{width: width, height: 8, input: []rune(input), cursorPos: cursor}
+				state.update(manyChallenges(3), SearchOptions{})
+
+				var buf bytes.Buffer
+				displaySearchUIWithCursor(&buf, state)
+				if !strings.Contains(buf.String(), "input [substring]") {
+					t.Errorf("Width %d, cursor %d: expected the input line, got %q", width, cursor, buf.String())
+				}
+			}
+		}
+	}
+}
+
+func TestDisplaySearchUIWithCursorPreview(t *testing.T) {
+	state := &searchState{width: 100, height: 16, preview: true}
+	state.update(testChallenges(), SearchOptions{})
+	state.moveSelection(1)
+
+	selected, _ := state.selectedResult()
+	state.previews = map[string]*challengePreview{
+		previewKey(selected): {
+			challenge:   &Challenge{Author: "OSINT Team", Description: "Find the location", Tags: selected.Tags},
+			publicFiles: []string{"public/photo.jpg"},
+		},
+	}
+
+	var buf bytes.Buffer
+	displaySearchUIWithCursor(&buf, state)
+	output := buf.String()
+
+	for _, want := range []string{"│\033[0m Name: Geolocation Challenge", "│\033[0m Author: OSINT Team", "│\033[0m   public/photo.jpg"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected preview pane to contain %q, got:\n%s", want, output)
+		}
+	}
+	for i, line := range strings.Split(output, "\r\n") {
+		if w := visibleWidth(line); w > 100 {
+			t.Errorf("Line %d exceeds terminal width (%d columns): %q", i, w, line)
+		}
+	}
+
+	// The preview pane is hidden when toggled off
+	state.preview = false
+	buf.Reset()
+	displaySearchUIWithCursor(&buf, state)
+	if strings.Contains(buf.String(), "│") {
+		t.Error("Expected no preview pane when disabled")
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected string
+	}{
+		{name: "Fits", input: "web", width: 3, expected: "web"},
+		{name: "Cut", input: "geolocation", width: 5, expected: "geol…\033[22;39m"},
+		{name: "Wide runes", input: "位置情報", width: 5, expected: "位置…\033[22;39m"},
+		{name: "Escape sequences are not counted", input: "\033[1;33mgeo\033[22;39mlocation", width: 5, expected: "\033[1;33mgeo\033[22;39ml…\033[22;39m"},
+		{name: "Zero width", input: "web", width: 0, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateToWidth(tt.input, tt.width); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"unicode"
+)
+
+// Editing operations on the interactive search prompt. They follow GNU
+// readline (emacs mode) semantics and keep cursorPos within [0, len(input)].
+
+// insertRune inserts a character at the cursor
+func (s *searchState) insertRune(r rune) {
+	s.input = append(s.input[:s.cursorPos], append([]rune{r}, s.input[s.cursorPos:]...)...)
+	s.cursorPos++
+}
+
+// deleteBackward removes the character before the cursor (Backspace)
+func (s *searchState) deleteBackward() {
+	if s.cursorPos == 0 {
+		return
+	}
+	s.input = append(s.input[:s.cursorPos-1], s.input[s.cursorPos:]...)
+	s.cursorPos--
+}
+
+// deleteForward removes the character under the cursor (Delete)
+func (s *searchState) deleteForward() {
+	if s.cursorPos >= len(s.input) {
+		return
+	}
+	s.input = append(s.input[:s.cursorPos], s.input[s.cursorPos+1:]...)
+}
+
+// moveLeft moves the cursor one character left
+func (s *searchState) moveLeft() {
+	if s.cursorPos > 0 {
+		s.cursorPos--
+	}
+}
+
+// moveRight moves the cursor one character right
+func (s *searchState) moveRight() {
+	if s.cursorPos < len(s.input) {
+		s.cursorPos++
+	}
+}
+
+// moveHome moves the cursor to the start of the line (Home, Ctrl-A)
+func (s *searchState) moveHome() {
+	s.cursorPos = 0
+}
+
+// moveEnd moves the cursor to the end of the line (End, Ctrl-E)
+func (s *searchState) moveEnd() {
+	s.cursorPos = len(s.input)
+}
+
+// moveWordBackward moves the cursor to the start of the current or previous
+// word, where words are runs of letters and digits (Alt-B)
+func (s *searchState) moveWordBackward() {
+	s.cursorPos = s.wordStart(isWordRune)
+}
+
+// moveWordForward moves the cursor to the end of the current or next word (Alt-F)
+func (s *searchState) moveWordForward() {
+	pos := s.cursorPos
+	for pos < len(s.input) && !isWordRune(s.input[pos]) {
+		pos++
+	}
+	for pos < len(s.input) && isWordRune(s.input[pos]) {
+		pos++
+	}
+	s.cursorPos = pos
+}
+
+// deleteWordBackward removes the whitespace-delimited word before the cursor (Ctrl-W)
+func (s *searchState) deleteWordBackward() {
+	start := s.wordStart(func(r rune) bool { return !unicode.IsSpace(r) })
+	s.input = append(s.input[:start], s.input[s.cursorPos:]...)
+	s.cursorPos = start
+}
+
+// killToStart removes everything before the cursor (Ctrl-U)
+func (s *searchState) killToStart() {
+	s.input = append([]rune(nil), s.input[s.cursorPos:]...)
+	s.cursorPos = 0
+}
+
+// killToEnd removes everything from the cursor to the end of the line (Ctrl-K)
+func (s *searchState) killToEnd() {
+	s.input = s.input[:s.cursorPos]
+}
+
+// wordStart returns the position reached by skipping backwards over
+// non-word runes and then over word runes, as judged by inWord
+func (s *searchState) wordStart(inWord func(rune) bool) int {
+	pos := s.cursorPos
+	for pos > 0 && !inWord(s.input[pos-1]) {
+		pos--
+	}
+	for pos > 0 && inWord(s.input[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// isWordRune reports whether r is part of a word for Alt-B/Alt-F movement
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
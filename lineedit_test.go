@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLineEditing(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		cursor      int
+		edit        func(s *searchState)
+		expected    string
+		expectedPos int
+	}{
+		{name: "Insert in the middle", input: "wb", cursor: 1, edit: func(s *searchState) { s.insertRune('e') }, expected: "web", expectedPos: 2},
+		{name: "Backspace", input: "web", cursor: 3, edit: (*searchState).deleteBackward, expected: "we", expectedPos: 2},
+		{name: "Backspace at start", input: "web", cursor: 0, edit: (*searchState).deleteBackward, expected: "web", expectedPos: 0},
+		{name: "Delete forward", input: "web", cursor: 0, edit: (*searchState).deleteForward, expected: "eb", expectedPos: 0},
+		{name: "Delete forward at end", input: "web", cursor: 3, edit: (*searchState).deleteForward, expected: "web", expectedPos: 3},
+		{name: "Home", input: "web", cursor: 2, edit: (*searchState).moveHome, expected: "web", expectedPos: 0},
+		{name: "End", input: "web", cursor: 1, edit: (*searchState).moveEnd, expected: "web", expectedPos: 3},
+		{name: "Ctrl-W deletes previous word", input: "easy sql-injection", cursor: 18, edit: (*searchState).deleteWordBackward, expected: "easy ", expectedPos: 5},
+		{name: "Ctrl-W skips trailing spaces", input: "easy osint  ", cursor: 12, edit: (*searchState).deleteWordBackward, expected: "easy ", expectedPos: 5},
+		{name: "Ctrl-U", input: "easy osint", cursor: 5, edit: (*searchState).killToStart, expected: "osint", expectedPos: 0},
+		{name: "Ctrl-K", input: "easy osint", cursor: 4, edit: (*searchState).killToEnd, expected: "easy", expectedPos: 4},
+		{name: "Alt-B stops at punctuation", input: "sql-injection", cursor: 13, edit: (*searchState).moveWordBackward, expected: "sql-injection", expectedPos: 4},
+		{name: "Alt-F", input: "easy osint", cursor: 0, edit: (*searchState).moveWordForward, expected: "easy osint", expectedPos: 4},
+		{name: "Alt-F skips separators", input: "easy osint", cursor: 4, edit: (*searchState).moveWordForward, expected: "easy osint", expectedPos: 10},
+		{name: "Multibyte word", input: "位置 情報", cursor: 5, edit: (*searchState).moveWordBackward, expected: "位置 情報", expectedPos: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &searchState{input: []rune(tt.input), cursorPos: tt.cursor}
+			tt.edit(state)
+
+			if string(state.input) != tt.expected {
+				t.Errorf("Expected input %q, got %q", tt.expected, string(state.input))
+			}
+			if state.cursorPos != tt.expectedPos {
+				t.Errorf("Expected cursor at %d, got %d", tt.expectedPos, state.cursorPos)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lintSchemaVersion is the version of the JSON written by `searchall lint --format json`
+const lintSchemaVersion = 1
+
+// Severity of a lint diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single problem found in a challenge.yml
+type Diagnostic struct {
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// String formats the diagnostic as "path:line:column: severity: message (rule)"
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", d.Path, d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// lintFile is a challenge.yml prepared for the lint rules
+type lintFile struct {
+	Path      string
+	Genre     string
+	Root      *yaml.Node // Top-level mapping, nil for an empty file
+	Challenge Challenge
+}
+
+// field returns the key and value nodes of a top-level key, or nils if absent
+func (f *lintFile) field(key string) (*yaml.Node, *yaml.Node) {
+	if f.Root == nil {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(f.Root.Content); i += 2 {
+		if f.Root.Content[i].Value == key {
+			return f.Root.Content[i], f.Root.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// diagnostic creates a diagnostic located at node, or at the start of the
+// file when node is nil
+func (f *lintFile) diagnostic(node *yaml.Node, message string) Diagnostic {
+	d := Diagnostic{Path: f.Path, Line: 1, Column: 1, Message: message}
+	if node != nil {
+		d.Line, d.Column = node.Line, node.Column
+	}
+	return d
+}
+
+// LintConfig is the `lint:` section of config.yaml
+type LintConfig struct {
+	Disable   []string `yaml:"disable"`    // Rules to skip
+	KnownKeys []string `yaml:"known_keys"` // Extra top-level keys allowed in challenge.yml
+}
+
+// lintContext is everything a rule can inspect
+type lintContext struct {
+	Files     []*lintFile
+	Taxonomy  *Taxonomy
+	Aliases   map[string][]string
+	KnownKeys []string // Top-level keys accepted besides the Challenge fields
+}
+
+// lintRule checks the challenges and returns the problems it finds. Rules see
+// every file at once so that they can compare challenges with each other.
+type lintRule struct {
+	Name        string
+	Severity    Severity
+	Description string
+	Check       func(ctx *lintContext) []Diagnostic
+}
+
+// lintRules is the rule set run by `searchall lint`. New rules are added here.
+var lintRules = []lintRule{
+	{"missing-name", SeverityError, "name is missing or empty", checkRequiredField("name")},
+	{"empty-tags", SeverityError, "tags is missing or empty", checkEmptyTags},
+	{"duplicate-name", SeverityError, "the same name is used by several challenges", checkDuplicateNames},
+	{"unknown-tag", SeverityWarning, "a tag is neither in the taxonomy nor an alias (only with a taxonomy)", checkUnknownTags},
+	{"missing-description", SeverityWarning, "description is missing or empty", checkRequiredField("description")},
+	{"missing-author", SeverityWarning, "author is missing or empty", checkRequiredField("author")},
+	{"empty-public", SeverityWarning, "public/ exists or is referenced but has no files", checkPublicFiles},
+	{"unknown-key", SeverityWarning, "a top-level key is not a known challenge field", checkUnknownKeys},
+}
+
+// challengeKeys are the top-level keys decoded into Challenge
+var challengeKeys = []string{"name", "description", "author", "flag", "tags"}
+
+// checkRequiredField returns a check reporting files where key is missing or blank
+func checkRequiredField(key string) func(ctx *lintContext) []Diagnostic {
+	return func(ctx *lintContext) []Diagnostic {
+		var diagnostics []Diagnostic
+		for _, file := range ctx.Files {
+			keyNode, value := file.field(key)
+			switch {
+			case keyNode == nil:
+				diagnostics = append(diagnostics, file.diagnostic(nil, fmt.Sprintf("%s is missing", key)))
+			case value.Kind != yaml.ScalarNode || strings.TrimSpace(value.Value) == "":
+				diagnostics = append(diagnostics, file.diagnostic(keyNode, fmt.Sprintf("%s is empty", key)))
+			}
+		}
+		return diagnostics
+	}
+}
+
+// checkEmptyTags reports challenges without tags, which no tag search can find
+func checkEmptyTags(ctx *lintContext) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, file := range ctx.Files {
+		keyNode, value := file.field("tags")
+		switch {
+		case keyNode == nil:
+			diagnostics = append(diagnostics, file.diagnostic(nil, "tags is missing"))
+		case value.Kind != yaml.SequenceNode || len(value.Content) == 0:
+			diagnostics = append(diagnostics, file.diagnostic(keyNode, "tags is empty"))
+		}
+	}
+	return diagnostics
+}
+
+// checkDuplicateNames reports challenges sharing a name, ignoring case, even
+// across genres
+func checkDuplicateNames(ctx *lintContext) []Diagnostic {
+	byName := make(map[string][]*lintFile)
+	for _, file := range ctx.Files {
+		name := strings.ToLower(strings.TrimSpace(file.Challenge.Name))
+		if name != "" {
+			byName[name] = append(byName[name], file)
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for _, file := range ctx.Files {
+		name := strings.ToLower(strings.TrimSpace(file.Challenge.Name))
+		if len(byName[name]) < 2 || name == "" {
+			continue
+		}
+		var others []string
+		for _, other := range byName[name] {
+			if other != file {
+				others = append(others, other.Path)
+			}
+		}
+		_, value := file.field("name")
+		diagnostics = append(diagnostics, file.diagnostic(value,
+			fmt.Sprintf("name %q is also used by %s", file.Challenge.Name, strings.Join(others, ", "))))
+	}
+	return diagnostics
+}
+
+// checkUnknownTags reports tags that the taxonomy and aliases do not know,
+// which usually are typos or new synonyms of existing tags
+func checkUnknownTags(ctx *lintContext) []Diagnostic {
+	if ctx.Taxonomy == nil {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	for _, file := range ctx.Files {
+		_, value := file.field("tags")
+		if value == nil || value.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				continue
+			}
+			if ctx.Taxonomy.contains(item.Value) || ctx.Aliases[strings.ToLower(item.Value)] != nil {
+				continue
+			}
+			diagnostics = append(diagnostics, file.diagnostic(item, fmt.Sprintf("tag %q is not in the taxonomy", item.Value)))
+		}
+	}
+	return diagnostics
+}
+
+// checkPublicFiles reports public/ directories without files, and references
+// to files under public/ that do not exist
+func checkPublicFiles(ctx *lintContext) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, file := range ctx.Files {
+		dir := filepath.Dir(file.Path)
+		public := filepath.Join(dir, "public")
+
+		// Any string value starting with public/ counts as a reference
+		var referenced []*yaml.Node
+		walkScalars(file.Root, func(node *yaml.Node) {
+			if node.Value == "public" || strings.HasPrefix(node.Value, "public/") {
+				referenced = append(referenced, node)
+			}
+		})
+		for _, node := range referenced {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(node.Value))); err != nil {
+				diagnostics = append(diagnostics, file.diagnostic(node, fmt.Sprintf("%s does not exist", node.Value)))
+			}
+		}
+
+		info, err := os.Stat(public)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		var node *yaml.Node
+		if len(referenced) > 0 {
+			node = referenced[0]
+		}
+		found, err := hasFiles(public)
+		switch {
+		case err != nil:
+			diagnostics = append(diagnostics, file.diagnostic(node, fmt.Sprintf("cannot read public/ directory: %v", err)))
+		case !found:
+			diagnostics = append(diagnostics, file.diagnostic(node, "public/ directory has no files"))
+		}
+	}
+	return diagnostics
+}
+
+// checkUnknownKeys reports top-level keys that are neither challenge fields
+// nor listed in lint.known_keys, which catches typos such as "tag:"
+func checkUnknownKeys(ctx *lintContext) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, file := range ctx.Files {
+		if file.Root == nil {
+			continue
+		}
+		for i := 0; i+1 < len(file.Root.Content); i += 2 {
+			key := file.Root.Content[i]
+			if containsFold(challengeKeys, key.Value) || containsFold(ctx.KnownKeys, key.Value) {
+				continue
+			}
+			diagnostics = append(diagnostics, file.diagnostic(key, fmt.Sprintf("unknown key %q", key.Value)))
+		}
+	}
+	return diagnostics
+}
+
+// walkScalars calls fn for every scalar value below node, skipping mapping keys
+func walkScalars(node *yaml.Node, fn func(*yaml.Node)) {
+	if node == nil {
+		return
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		fn(node)
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			walkScalars(node.Content[i], fn)
+		}
+	default:
+		for _, child := range node.Content {
+			walkScalars(child, fn)
+		}
+	}
+}
+
+// hasFiles reports whether dir contains a regular file at any depth. An
+// entry that cannot be read stops the walk with its error.
+func hasFiles(dir string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+// yamlErrorLine finds the line number in a yaml.v3 error message
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLineNumber returns the line an error from yaml.v3 refers to, or 1
+func yamlErrorLineNumber(err error) int {
+	if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+		if line, err := strconv.Atoi(m[1]); err == nil {
+			return line
+		}
+	}
+	return 1
+}
+
+// loadLintFiles parses every challenge.yml under the genre directories. Files
+// that are not valid YAML are returned as "parse" diagnostics instead.
+func loadLintFiles(genres []string) ([]*lintFile, []Diagnostic, error) {
+	var files []*lintFile
+	var diagnostics []Diagnostic
+
+	for _, genre := range genres {
+		if _, err := os.Stat(genre); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.WalkDir(genre, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || d.Name() != "challenge.yml" {
+				return nil
+			}
+
+			file, diagnostic := parseLintFile(path, genre)
+			if file != nil {
+				files = append(files, file)
+			} else {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to walk directory %s: %w", genre, err)
+		}
+	}
+	return files, diagnostics, nil
+}
+
+// parseLintFile reads a challenge.yml, returning a diagnostic when it cannot be parsed
+func parseLintFile(path, genre string) (*lintFile, Diagnostic) {
+	fail := func(line int, message string) (*lintFile, Diagnostic) {
+		return nil, Diagnostic{Path: path, Line: line, Column: 1, Severity: SeverityError, Rule: "parse", Message: message}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail(1, err.Error())
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fail(yamlErrorLineNumber(err), err.Error())
+	}
+
+	file := &lintFile{Path: path, Genre: genre}
+	if len(doc.Content) == 0 {
+		return file, Diagnostic{}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fail(root.Line, "challenge.yml must be a mapping")
+	}
+	// Values of the wrong type, e.g. a string for tags, fail here
+	if err := root.Decode(&file.Challenge); err != nil {
+		return fail(yamlErrorLineNumber(err), err.Error())
+	}
+	file.Root = root
+	return file, Diagnostic{}
+}
+
+// runLintRules runs the enabled rules and returns their diagnostics sorted by
+// location
+func runLintRules(ctx *lintContext, rules []lintRule) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, rule := range rules {
+		for _, d := range rule.Check(ctx) {
+			d.Rule = rule.Name
+			d.Severity = rule.Severity
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// sortDiagnostics orders diagnostics by file, line and column
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// selectLintRules returns the rules that are not disabled, rejecting unknown
+// names. Names are compared case-insensitively.
+func selectLintRules(disabled []string) ([]lintRule, error) {
+	for _, name := range disabled {
+		known := false
+		for _, rule := range lintRules {
+			known = known || strings.EqualFold(rule.Name, name)
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+	}
+
+	var rules []lintRule
+	for _, rule := range lintRules {
+		if !containsFold(disabled, rule.Name) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// runLintCommand implements `searchall lint`. It reports whether the
+// challenges failed the check: any error, or any warning with --strict.
+func runLintCommand(args []string) (bool, error) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	disableFlag := flags.String("disable", "", "Comma-separated rules to skip (added to lint.disable in config.yaml)")
+	strict := flags.Bool("strict", false, "Fail on warnings as well as errors")
+	formatFlag := flags.String("format", "text", "Output format: text or json")
+	listRules := flags.Bool("list-rules", false, "List the available rules and exit")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+
+	if *listRules {
+		writeLintRules(os.Stdout)
+		return false, nil
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		return false, fmt.Errorf("unknown output format %q (expected text or json)", *formatFlag)
+	}
+
+	config, err := loadConfig("config.yaml")
+	if err != nil {
+		return false, fmt.Errorf("failed to load config.yaml: %w", err)
+	}
+	taxonomy, err := loadTaxonomy(config)
+	if err != nil {
+		return false, err
+	}
+
+	disabled := append([]string(nil), config.Lint.Disable...)
+	if *disableFlag != "" {
+		for _, name := range strings.Split(*disableFlag, ",") {
+			disabled = append(disabled, strings.TrimSpace(name))
+		}
+	}
+	rules, err := selectLintRules(disabled)
+	if err != nil {
+		return false, err
+	}
+
+	files, diagnostics, err := loadLintFiles(config.Genre)
+	if err != nil {
+		return false, err
+	}
+	ctx := &lintContext{
+		Files:     files,
+		Taxonomy:  taxonomy,
+		Aliases:   newAliasTable(config.Aliases),
+		KnownKeys: config.Lint.KnownKeys,
+	}
+	diagnostics = append(diagnostics, runLintRules(ctx, rules)...)
+	sortDiagnostics(diagnostics)
+
+	if *formatFlag == "json" {
+		if err := writeDiagnosticsJSON(os.Stdout, diagnostics); err != nil {
+			return false, err
+		}
+	} else {
+		writeDiagnostics(os.Stdout, diagnostics)
+	}
+
+	errors, warnings := countSeverities(diagnostics)
+	if *formatFlag == "text" && len(diagnostics) > 0 {
+		fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s) in %d file(s)\n", errors, warnings, len(files))
+	}
+	return errors > 0 || (*strict && warnings > 0), nil
+}
+
+// countSeverities counts the errors and warnings among diagnostics
+func countSeverities(diagnostics []Diagnostic) (errors, warnings int) {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// writeDiagnostics writes one diagnostic per line
+func writeDiagnostics(w io.Writer, diagnostics []Diagnostic) {
+	for _, d := range diagnostics {
+		fmt.Fprintln(w, d)
+	}
+}
+
+// writeDiagnosticsJSON writes the diagnostics as a versioned JSON document
+func writeDiagnosticsJSON(w io.Writer, diagnostics []Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Version     int          `json:"version"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}{lintSchemaVersion, diagnostics})
+}
+
+// writeLintRules lists the rule set with severities and descriptions
+func writeLintRules(w io.Writer) {
+	for _, rule := range lintRules {
+		fmt.Fprintf(w, "%-20s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+	}
+}
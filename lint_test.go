@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeLintTree creates files from a map of path to content in a temporary
+// directory and changes into it. Paths ending in a slash are empty directories.
+func writeLintTree(t *testing.T, files map[string]string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, filepath.FromSlash(path))
+		if strings.HasSuffix(path, "/") {
+			if err := os.MkdirAll(fullPath, 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	oldWd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(oldWd) })
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+}
+
+// lintTree runs every rule over the genres and returns the formatted diagnostics
+func lintTree(t *testing.T, genres []string, taxonomy *Taxonomy) []string {
+	t.Helper()
+	files, diagnostics, err := loadLintFiles(genres)
+	if err != nil {
+		t.Fatalf("Failed to load challenges: %v", err)
+	}
+	ctx := &lintContext{Files: files, Taxonomy: taxonomy, KnownKeys: []string{"value"}}
+	diagnostics = append(diagnostics, runLintRules(ctx, lintRules)...)
+	sortDiagnostics(diagnostics)
+
+	var lines []string
+	for _, d := range diagnostics {
+		lines = append(lines, d.String())
+	}
+	return lines
+}
+
+func TestLintRules(t *testing.T) {
+	writeLintTree(t, map[string]string{
+		"web/ok/challenge.yml":           "name: OK\ndescription: fine\nauthor: me\ntags: [easy]\nvalue: 100\n",
+		"web/ok/public/file.txt":         "data",
+		"web/bare/challenge.yml":         "flag: flag{x}\n",
+		"web/empty/challenge.yml":        "name: \"\"\ndescription: d\nauthor: a\ntags: []\n",
+		"web/dup/challenge.yml":          "name: Same\ndescription: d\nauthor: a\ntags: [easy]\n",
+		"osint/dup/challenge.yml":        "description: d\nauthor: a\nname: same\ntags: [easy]\n",
+		"osint/typo/challenge.yml":       "name: Typo\ndescription: d\nauthor: a\ntag: [easy]\n",
+		"osint/nopublic/challenge.yml":   "name: Files\ndescription: d\nauthor: a\ntags: [easy]\nfiles:\n  - public/missing.png\n",
+		"osint/nopublic/public/":         "",
+		"osint/broken/challenge.yml":     "name: Broken\ntags: [easy\n",
+		"osint/wrongtype/challenge.yml":  "name: Wrong\ntags: easy\n",
+		"osint/emptyfile/challenge.yml":  "",
+		"osint/ignored/notchallenge.yml": "name: Ignored\n",
+	})
+
+	got := lintTree(t, []string{"web", "osint"}, nil)
+	expected := []string{
+		"osint/broken/challenge.yml:1:1: error: yaml: line 1: did not find expected ',' or ']' (parse)",
+		"osint/dup/challenge.yml:3:7: error: name \"same\" is also used by web/dup/challenge.yml (duplicate-name)",
+		"osint/emptyfile/challenge.yml:1:1: error: name is missing (missing-name)",
+		"osint/emptyfile/challenge.yml:1:1: error: tags is missing (empty-tags)",
+		"osint/emptyfile/challenge.yml:1:1: warning: description is missing (missing-description)",
+		"osint/emptyfile/challenge.yml:1:1: warning: author is missing (missing-author)",
+		"osint/nopublic/challenge.yml:5:1: warning: unknown key \"files\" (unknown-key)",
+		"osint/nopublic/challenge.yml:6:5: warning: public/missing.png does not exist (empty-public)",
+		"osint/nopublic/challenge.yml:6:5: warning: public/ directory has no files (empty-public)",
+		"osint/typo/challenge.yml:1:1: error: tags is missing (empty-tags)",
+		"osint/typo/challenge.yml:4:1: warning: unknown key \"tag\" (unknown-key)",
+		"osint/wrongtype/challenge.yml:2:1: error: yaml: unmarshal errors:\n  line 2: cannot unmarshal !!str `easy` into []string (parse)",
+		"web/bare/challenge.yml:1:1: error: name is missing (missing-name)",
+		"web/bare/challenge.yml:1:1: error: tags is missing (empty-tags)",
+		"web/bare/challenge.yml:1:1: warning: description is missing (missing-description)",
+		"web/bare/challenge.yml:1:1: warning: author is missing (missing-author)",
+		"web/dup/challenge.yml:1:7: error: name \"Same\" is also used by osint/dup/challenge.yml (duplicate-name)",
+		"web/empty/challenge.yml:1:1: error: name is empty (missing-name)",
+		"web/empty/challenge.yml:4:1: error: tags is empty (empty-tags)",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\n\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestLintUnknownTags(t *testing.T) {
+	writeLintTree(t, map[string]string{
+		"web/a/challenge.yml": "name: A\ndescription: d\nauthor: a\ntags:\n  - easy\n  - sqli\n  - eazy\n",
+	})
+
+	var taxonomy Taxonomy
+	if err := yaml.Unmarshal([]byte("difficulty: [easy, hard]\n"), &taxonomy); err != nil {
+		t.Fatalf("Failed to parse taxonomy: %v", err)
+	}
+	files, _, err := loadLintFiles([]string{"web"})
+	if err != nil {
+		t.Fatalf("Failed to load challenges: %v", err)
+	}
+
+	ctx := &lintContext{Files: files, Taxonomy: &taxonomy, Aliases: newAliasTable(map[string][]string{"sqli": {"sql-injection"}})}
+	diagnostics := checkUnknownTags(ctx)
+	if len(diagnostics) != 1 || diagnostics[0].Line != 7 || !strings.Contains(diagnostics[0].Message, `"eazy"`) {
+		t.Errorf("Expected only the typo to be reported, got %v", diagnostics)
+	}
+
+	ctx.Taxonomy = nil
+	if diagnostics := checkUnknownTags(ctx); diagnostics != nil {
+		t.Errorf("Expected no diagnostics without a taxonomy, got %v", diagnostics)
+	}
+}
+
+func TestSelectLintRules(t *testing.T) {
+	// Rule names are matched case-insensitively
+	rules, err := selectLintRules([]string{"unknown-key", "Missing-Author"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != len(lintRules)-2 {
+		t.Errorf("Expected %d rules, got %d", len(lintRules)-2, len(rules))
+	}
+	for _, rule := range rules {
+		if rule.Name == "unknown-key" || rule.Name == "missing-author" {
+			t.Errorf("Expected %s to be disabled", rule.Name)
+		}
+	}
+
+	if _, err := selectLintRules([]string{"no-such-rule"}); err == nil {
+		t.Error("Expected error for unknown rule")
+	}
+}
+
+func TestWriteDiagnosticsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeDiagnosticsJSON(&buf, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"diagnostics": []`) {
+		t.Errorf("Expected an empty diagnostics list, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	diagnostics := []Diagnostic{{Path: "web/a/challenge.yml", Line: 2, Column: 1, Severity: SeverityError, Rule: "missing-name", Message: "name is missing"}}
+	if err := writeDiagnosticsJSON(&buf, diagnostics); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var doc struct {
+		Version     int          `json:"version"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Version != lintSchemaVersion || len(doc.Diagnostics) != 1 || doc.Diagnostics[0] != diagnostics[0] {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+
+	if errors, warnings := countSeverities(diagnostics); errors != 1 || warnings != 0 {
+		t.Errorf("Expected 1 error and 0 warnings, got %d and %d", errors, warnings)
+	}
+}
+
+func TestHasFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "empty", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "full", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "full", "sub", "file.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := hasFiles(filepath.Join(dir, "full")); !found || err != nil {
+		t.Errorf("Expected a nested file to be found, got %v, %v", found, err)
+	}
+	if found, err := hasFiles(filepath.Join(dir, "empty")); found || err != nil {
+		t.Errorf("Expected no files in empty directories, got %v, %v", found, err)
+	}
+	// Read errors are reported rather than taken for an empty directory
+	if _, err := hasFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a directory that cannot be read")
+	}
+}
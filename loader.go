@@ -1,17 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
 )
 
 // ChallengeLoader is an interface for loading challenges from various sources
 type ChallengeLoader interface {
-	LoadChallenges(genres []string) ([]ChallengeResult, error)
+	// LoadChallenges loads the challenges under the genre directories. It
+	// stops early with the context's error when ctx is cancelled.
+	LoadChallenges(ctx context.Context, genres []string) ([]ChallengeResult, error)
 }
 
 // FileSystemLoader loads challenges from the current working directory (file system)
@@ -20,7 +21,7 @@ type FileSystemLoader struct {
 }
 
 // LoadChallenges loads all challenges from the file system (existing logic)
-func (f *FileSystemLoader) LoadChallenges(genres []string) ([]ChallengeResult, error) {
+func (f *FileSystemLoader) LoadChallenges(ctx context.Context, genres []string) ([]ChallengeResult, error) {
 	var allChallenges []ChallengeResult
 
 	for _, genre := range genres {
@@ -32,6 +33,9 @@ func (f *FileSystemLoader) LoadChallenges(genres []string) ([]ChallengeResult, e
 			if err != nil {
 				return err
 			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 
 			if d.IsDir() || d.Name() != "challenge.yml" {
 				return nil
@@ -39,16 +43,15 @@ func (f *FileSystemLoader) LoadChallenges(genres []string) ([]ChallengeResult, e
 
 			challenge, err := loadChallenge(path)
 			if err != nil {
-				fmt.Printf("Warning: Failed to load %s: %v\n", path, err)
+				fmt.Fprintf(os.Stderr, "Warning: Failed to load %s: %v\n", path, err)
 				return nil
 			}
 
-			allChallenges = append(allChallenges, ChallengeResult{
-				Name:       challenge.Name,
-				Tags:       challenge.Tags,
-				FilePath:   path,
-				BranchName: f.BranchName,
-			})
+			result := newChallengeResult(challenge, genre, path, f.BranchName, "")
+			if info, err := d.Info(); err == nil {
+				result.ModTime = info.ModTime()
+			}
+			allChallenges = append(allChallenges, result)
 
 			return nil
 		})
@@ -64,40 +67,39 @@ func (f *FileSystemLoader) LoadChallenges(genres []string) ([]ChallengeResult, e
 // GitBranchLoader loads challenges from a specific Git branch
 type GitBranchLoader struct {
 	BranchName string
+	Git        GitRepository   // Optional: defaults to the repository in the current directory
+	Index      *ChallengeIndex // Optional: reuses the files read by earlier runs
 }
 
 // LoadChallenges loads all challenges from the specified Git branch
-func (g *GitBranchLoader) LoadChallenges(genres []string) ([]ChallengeResult, error) {
+func (g *GitBranchLoader) LoadChallenges(ctx context.Context, genres []string) ([]ChallengeResult, error) {
+	repo := repositoryOr(g.Git)
 	var challenges []ChallengeResult
 
 	for _, genre := range genres {
-		files, err := listChallengeFilesInBranch(g.BranchName, genre)
+		// A genre missing from this branch has no entries
+		entries, err := g.Index.listing(repo, g.BranchName, genre)
 		if err != nil {
-			// Genre might not exist in this branch, skip
-			continue
+			fmt.Fprintf(os.Stderr, "Warning: Failed to list %s in branch %s: %v\n", genre, g.BranchName, err)
 		}
-
-		for _, file := range files {
-			content, err := getFileContentFromBranch(g.BranchName, file)
-			if err != nil {
-				// File might not exist or be readable, skip
-				continue
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
 
-			var challenge Challenge
-			if err := yaml.Unmarshal(content, &challenge); err != nil {
-				fmt.Printf("Warning: Failed to parse %s in branch %s: %v\n", file, g.BranchName, err)
+			blob := g.Index.challenge(repo, entry.OID)
+			if blob.parseErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to parse %s in branch %s: %v\n", entry.Path, g.BranchName, blob.parseErr)
+			}
+			// Files that cannot be read are skipped
+			if blob.challenge == nil {
 				continue
 			}
 
-			challenges = append(challenges, ChallengeResult{
-				Name:       challenge.Name,
-				Tags:       challenge.Tags,
-				FilePath:   file,
-				BranchName: g.BranchName,
-			})
+			challenges = append(challenges, newChallengeResult(blob.challenge, genre, entry.Path, g.BranchName, g.BranchName))
 		}
 	}
 
+	saveIndex(repo, g.Index)
 	return challenges, nil
 }
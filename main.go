@@ -1,41 +1,153 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the config.yaml structure
 type Config struct {
-	Genre []string `yaml:"genre"`
+	Genre        []string            `yaml:"genre"`
+	Match        string              `yaml:"match"`         // Default tag match mode, see matchModeNames
+	Keys         map[string]string   `yaml:"keys"`          // Interactive key bindings, action name to key
+	Aliases      map[string][]string `yaml:"aliases"`       // Tag synonyms, e.g. sqli: [sql-injection]
+	Taxonomy     *Taxonomy           `yaml:"taxonomy"`      // Tag hierarchy, e.g. difficulty: [easy, medium, hard]
+	TaxonomyFile string              `yaml:"taxonomy_file"` // File with more taxonomy entries
+	Lint         LintConfig          `yaml:"lint"`          // Settings for `searchall lint`
+	Audit        AuditConfig         `yaml:"audit"`         // Settings for `searchall audit`
+	Branches     BranchConfig        `yaml:"branches"`      // Default branch and branch priority for --all-branches
 }
 
 // Challenge represents the challenge.yml structure
 type Challenge struct {
-	Name string   `yaml:"name"`
-	Tags []string `yaml:"tags"`
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Author      string                 `yaml:"author"`
+	Flag        string                 `yaml:"flag"`
+	Tags        []string               `yaml:"tags"`
+	Extra       map[string]interface{} `yaml:",inline"` // Any other keys in challenge.yml
 }
 
 // ChallengeResult holds challenge information with its file path
 type ChallengeResult struct {
-	Name       string
-	Tags       []string
-	FilePath   string
-	BranchName string // Branch name where the challenge was found
+	Name        string
+	Description string
+	Author      string
+	Flag        string
+	Tags        []string
+	Extra       map[string]interface{}
+	Genre       string // Genre directory the challenge was found under
+	FilePath    string
+	BranchName  string    // Branch name where the challenge was found, for display
+	Ref         string    // Full name of the ref it was read from (refs/tags/v1), used for git reads; empty for the working tree
+	ModTime     time.Time // Last modification time, zero until known
+	Score       int       // Relevance score for the current query
+}
+
+// SearchOptions holds the settings shared by static and interactive search
+type SearchOptions struct {
+	Query       QueryOptions
+	Sort        SortKey
+	Keys        keyBindings     // Actions available in interactive mode
+	Format      OutputFormat    // Output format for search results
+	Template    *resultTemplate // Overrides Format when set
+	IncludeFlag bool            // Write the flags in the output formats and templates
+}
+
+// newChallengeResult builds a ChallengeResult from a parsed challenge.yml.
+// ref is the full name of the ref it was read from, or empty for the working tree.
+func newChallengeResult(challenge *Challenge, genre, filePath, branchName, ref string) ChallengeResult {
+	return ChallengeResult{
+		Genre:       genre,
+		Name:        challenge.Name,
+		Description: challenge.Description,
+		Author:      challenge.Author,
+		Flag:        challenge.Flag,
+		Tags:        challenge.Tags,
+		Extra:       challenge.Extra,
+		FilePath:    filePath,
+		BranchName:  branchName,
+		Ref:         ref,
+	}
 }
 
 func main() {
+	// Subcommands are dispatched on the first argument. A search for a tag
+	// with the same name can be written as `searchall -- tags`.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "tags":
+			if err := runTagsCommand(os.Args[2:]); err != nil {
+				exitIfInterrupted(err)
+				log.Fatalf("tags: %v", err)
+			}
+			return
+		case "lint":
+			failed, err := runLintCommand(os.Args[2:])
+			if err != nil {
+				log.Fatalf("lint: %v", err)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return
+		case "diff-branches":
+			differs, err := runDiffBranchesCommand(os.Args[2:])
+			if err != nil {
+				exitIfInterrupted(err)
+				log.Fatalf("diff-branches: %v", err)
+			}
+			if differs {
+				os.Exit(1)
+			}
+			return
+		case "audit":
+			failed, err := runAuditCommand(os.Args[2:])
+			if err != nil {
+				exitIfInterrupted(err)
+				log.Fatalf("audit: %v", err)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return
+		case "index":
+			if err := runIndexCommand(os.Args[2:]); err != nil {
+				exitIfInterrupted(err)
+				log.Fatalf("index: %v", err)
+			}
+			return
+		}
+	}
+
 	// Parse flags
-	allBranches := flag.Bool("all-branches", false, "Search challenges across all local branches")
-	flag.Parse()
+	allBranches := flag.Bool("all-branches", false, "Search challenges across the refs selected by --refs and --exclude-refs (local branches by default)")
+	jobs := flag.Int("jobs", 0, "Branches and files loaded in parallel with --all-branches (0: one per CPU)")
+	matchFlag := flag.String("match", "", "Default tag match mode: "+matchModeList()+" (overrides config.yaml)")
+	sortFlag := flag.String("sort", "score", "Result order: score, name, genre, path, branch or last-modified")
+	formatFlag := flag.String("format", "markdown", "Output format: markdown, markdown-table, json, jsonl, yaml, csv or tsv")
+	templateFlag := flag.String("template", "", "Go text/template rendered for each result, e.g. '{{.Name}} -> {{.FilePath}}'")
+	templateFileFlag := flag.String("template-file", "", "File containing the template (may define \"header\" and \"footer\" templates)")
+	headerFlag := flag.String("template-header", "", "Template rendered once before the results")
+	footerFlag := flag.String("template-footer", "", "Template rendered once after the results")
+	showConflicts := flag.Bool("show-conflicts", false, "Report challenges whose copies differ between branches (implies --all-branches)")
+	groupByFlag := flag.String("group-by", "", "Group results by genre, author, branch, tag or a top-level taxonomy tag")
+	includeFlag := flag.Bool("include-flag", false, "Write the flags in the output formats and templates instead of leaving them out")
+	refsFlags := addRefFlags(flag.CommandLine)
+	// flag.Parse would reject `searchall -beginner` as an unknown flag
+	_ = flag.CommandLine.Parse(queryArgs(flag.CommandLine, os.Args[1:]))
 
 	// Load config.yaml
 	config, err := loadConfig("config.yaml")
@@ -43,22 +155,86 @@ func main() {
 		log.Fatalf("Failed to load config.yaml: %v", err)
 	}
 
+	// The command-line flag takes precedence over config.yaml
+	matchName := config.Match
+	if *matchFlag != "" {
+		matchName = *matchFlag
+	}
+	defaultMode, err := parseMatchMode(matchName)
+	if err != nil {
+		log.Fatalf("Invalid match mode: %v", err)
+	}
+	sortKey, err := parseSortKey(*sortFlag)
+	if err != nil {
+		log.Fatalf("Invalid sort key: %v", err)
+	}
+	format, err := parseOutputFormat(*formatFlag)
+	if err != nil {
+		log.Fatalf("Invalid output format: %v", err)
+	}
+	resultTmpl, err := selectResultTemplate(*templateFlag, *templateFileFlag, *headerFlag, *footerFlag)
+	if err != nil {
+		log.Fatalf("Invalid template: %v", err)
+	}
+	if resultTmpl != nil && isFlagSet("format") {
+		log.Fatalf("--format cannot be combined with --template or --template-file")
+	}
+	refs, err := refsFlags()
+	if err != nil {
+		log.Fatalf("Invalid --refs: %v", err)
+	}
+	taxonomy, err := loadTaxonomy(config)
+	if err != nil {
+		log.Fatalf("Invalid taxonomy: %v", err)
+	}
+	if *groupByFlag != "" {
+		if resultTmpl != nil {
+			log.Fatalf("--group-by cannot be combined with --template or --template-file")
+		}
+		if err := validateFacet(*groupByFlag, taxonomy); err != nil {
+			log.Fatalf("Invalid --group-by: %v", err)
+		}
+	}
+	keys, err := newKeyBindings(config.Keys)
+	if err != nil {
+		log.Fatalf("Invalid key bindings in config.yaml: %v", err)
+	}
+	searchOpts := SearchOptions{
+		Query: QueryOptions{
+			DefaultMode: defaultMode,
+			Aliases:     newAliasTable(config.Aliases),
+			Taxonomy:    taxonomy,
+		},
+		Sort:        sortKey,
+		Keys:        keys,
+		Format:      format,
+		Template:    resultTmpl,
+		IncludeFlag: *includeFlag,
+	}
+
 	// Select appropriate loader
-	var loader ChallengeLoader
-	if *allBranches {
-		currentBranch, err := getCurrentBranch()
-		if err != nil {
-			log.Fatalf("Failed to get current branch: %v", err)
+	loader, err := newChallengeLoader(*allBranches || *showConflicts || !refs.IsZero(), *jobs, refs, config.Branches)
+	if err != nil {
+		log.Fatalf("Failed to select branches: %v", err)
+	}
+	multiLoader, _ := loader.(*MultiBranchLoader)
+	if multiLoader != nil {
+		multiLoader.DetectConflicts = *showConflicts
+		// The modification times need git log, so they are only looked up
+		// when sorting by them or printing them
+		if resultTmpl != nil {
+			multiLoader.ModTimes = sortKey == SortLastModified || resultTmpl.usesModTime()
+		} else {
+			multiLoader.ModTimes = sortKey == SortLastModified || format != FormatMarkdown
 		}
-		loader = &MultiBranchLoader{CurrentBranch: currentBranch}
-	} else {
-		// Use file system loader for backward compatibility
-		loader = &FileSystemLoader{BranchName: ""}
 	}
 
-	// Load all challenges once
-	allChallenges, err := loader.LoadChallenges(config.Genre)
+	// Load all challenges once; Ctrl-C aborts a slow load
+	ctx, stop := newInterruptContext()
+	allChallenges, err := loader.LoadChallenges(ctx, config.Genre)
+	stop()
 	if err != nil {
+		exitIfInterrupted(err)
 		log.Fatalf("Failed to load challenges: %v", err)
 	}
 
@@ -67,184 +243,170 @@ func main() {
 
 	if len(searchTags) == 0 {
 		// Interactive dynamic search mode
-		if err := interactiveSearch(allChallenges); err != nil {
+		if err := interactiveSearch(allChallenges, searchOpts); err != nil {
 			log.Fatalf("Interactive search failed: %v", err)
 		}
+		if multiLoader != nil {
+			reportConflicts(os.Stderr, multiLoader.Conflicts)
+		}
 	} else {
-		// Static search mode with provided tags
-		results := filterChallengesByTags(allChallenges, searchTags)
+		// Static search mode with provided query
+		results, err := filterChallengesByTags(allChallenges, searchTags, searchOpts.Query)
+		if err != nil {
+			log.Fatalf("Invalid query: %v", err)
+		}
+		sortResults(results, searchOpts.Sort)
+		if multiLoader != nil {
+			defer reportConflicts(os.Stderr, conflictsFor(multiLoader.Conflicts, results))
+		}
+
+		// The structured formats and templates write an empty document so that scripts can still parse it
+		if len(results) == 0 && searchOpts.Format == FormatMarkdown && searchOpts.Template == nil {
+			fmt.Printf("No challenges found for query: %s\n", strings.Join(searchTags, " "))
+			return
+		}
 
-		if len(results) == 0 {
-			fmt.Printf("No challenges found with tags: %s\n", strings.Join(searchTags, ", "))
+		if *groupByFlag != "" {
+			groups, err := groupResults(results, *groupByFlag, taxonomy)
+			if err != nil {
+				log.Fatalf("Invalid --group-by: %v", err)
+			}
+			if err := writeGroupedResults(os.Stdout, groups, searchOpts.Format, searchOpts.IncludeFlag); err != nil {
+				log.Fatalf("Failed to write results: %v", err)
+			}
 			return
 		}
 
-		// Display results in markdown format
-		displayMarkdownResults(results)
+		if err := outputResults(os.Stdout, results, searchOpts); err != nil {
+			log.Fatalf("Failed to write results: %v", err)
+		}
 	}
 }
 
-// interactiveSearch provides real-time interactive search
-func interactiveSearch(allChallenges []ChallengeResult) error {
-	// Save the original terminal state
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-	if err != nil {
-		return fmt.Errorf("failed to set raw mode: %w", err)
+// newChallengeLoader selects the loader for the working tree, or for the refs
+// selected by refs with --all-branches, reading up to jobs branches and files
+// at once and reusing the index of earlier runs
+func newChallengeLoader(allBranches bool, jobs int, refs RefFilter, branches BranchConfig) (ChallengeLoader, error) {
+	if !allBranches {
+		// Use file system loader for backward compatibility
+		return &FileSystemLoader{BranchName: ""}, nil
 	}
-	defer func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }()
-
-	// Clear screen and hide cursor
-	clearScreen()
-	defer fmt.Print("\033[?25h") // Show cursor on exit
 
-	var input []rune
-	var cursorPos int
-
-	// Display initial state
-	displaySearchUIWithCursor(string(input), cursorPos, allChallenges)
+	loader, err := newMultiBranchLoader(refs, branches, jobs)
+	if err != nil {
+		return nil, err
+	}
+	loader.Index = openDefaultIndex()
+	return loader, nil
+}
 
-	// Buffer for reading characters
-	buf := make([]byte, 3) // Support for escape sequences
+// newMultiBranchLoader creates a loader for the current repository with the
+// branch settings of config.yaml
+func newMultiBranchLoader(refs RefFilter, branches BranchConfig, jobs int) (*MultiBranchLoader, error) {
+	if err := branches.validate(); err != nil {
+		return nil, err
+	}
+	currentBranch, err := getCurrentBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current branch: %w", err)
+	}
+	loader := &MultiBranchLoader{
+		CurrentBranch: currentBranch,
+		DefaultBranch: branches.Default,
+		Priority:      branches.Priority,
+		Refs:          refs,
+		Jobs:          jobs,
+	}
+	// Each job reads through its own cat-file process, so the pool is sized
+	// by --jobs instead of the number of CPUs
+	if jobs > 0 {
+		loader.Git = &catFileRepository{MaxProcesses: jobs}
+	}
+	return loader, nil
+}
 
-	for {
-		// Read input
-		n, err := os.Stdin.Read(buf[:1])
-		if err != nil {
-			return fmt.Errorf("failed to read input: %w", err)
+// queryArgs inserts "--" before the first argument that is a "-term"
+// exclusion rather than a flag, so that a query may start with one. Only
+// single-dash arguments naming no defined flag are taken as terms; unknown
+// double-dash flags are still reported as errors.
+func queryArgs(flags *flag.FlagSet, args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			return args
 		}
-		if n == 0 {
-			continue
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+		if strings.HasPrefix(name, "-") {
+			name = strings.TrimPrefix(name, "-")
+		} else if flags.Lookup(name) == nil && name != "h" && name != "help" {
+			return append(append(args[:i:i], "--"), args[i:]...)
 		}
 
-		char := buf[0]
-
-		switch char {
-		case 3: // Ctrl+C
-			clearScreen()
-			fmt.Print("\033[?25h") // Show cursor
-			fmt.Println("Goodbye!")
-			return nil
-		case 27: // ESC - start of escape sequence
-			// Read the next two bytes for arrow keys
-			n, err := os.Stdin.Read(buf[1:3])
-			if err != nil || n < 2 {
-				continue
-			}
-
-			if buf[1] == '[' {
-				switch buf[2] {
-				case 'D': // Left arrow
-					if cursorPos > 0 {
-						cursorPos--
-						clearScreen()
-						displaySearchUIWithCursor(string(input), cursorPos, filterChallengesByInput(allChallenges, string(input)))
-					}
-				case 'C': // Right arrow
-					if cursorPos < len(input) {
-						cursorPos++
-						clearScreen()
-						displaySearchUIWithCursor(string(input), cursorPos, filterChallengesByInput(allChallenges, string(input)))
-					}
-				}
-			}
-		case 127, 8: // Backspace or Delete
-			if cursorPos > 0 && len(input) > 0 {
-				// Remove character before cursor
-				input = append(input[:cursorPos-1], input[cursorPos:]...)
-				cursorPos--
-
-				// Update display
-				clearScreen()
-				results := filterChallengesByInput(allChallenges, string(input))
-				displaySearchUIWithCursor(string(input), cursorPos, results)
-			}
-		case 13: // Enter
-			// Select first result if available
-			results := filterChallengesByInput(allChallenges, string(input))
-			if len(results) > 0 {
-				clearScreen()
-				fmt.Print("\033[?25h") // Show cursor
-				selected := results[0]
-				fmt.Printf("Selected: %s\n", selected.Name)
-				fmt.Printf("Tags: %s\n", strings.Join(selected.Tags, ", "))
-				fmt.Printf("Path: %s\n", selected.FilePath)
-				return nil
-			}
-		default:
-			// Add printable characters at cursor position
-			if char >= 32 && char <= 126 {
-				// Insert character at cursor position
-				input = append(input[:cursorPos], append([]rune{rune(char)}, input[cursorPos:]...)...)
-				cursorPos++
-
-				// Update display in real-time
-				clearScreen()
-				results := filterChallengesByInput(allChallenges, string(input))
-				displaySearchUIWithCursor(string(input), cursorPos, results)
+		// Skip the value of a non-boolean flag given as the next argument
+		if f := flags.Lookup(name); f != nil && !hasValue {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
 			}
 		}
 	}
+	return args
 }
 
-// clearScreen clears the entire screen and moves cursor to top-left
-func clearScreen() {
-	fmt.Print("\033[2J")   // Clear entire screen
-	fmt.Print("\033[H")    // Move cursor to home position (1,1)
-	fmt.Print("\033[?25l") // Hide cursor
+// newInterruptContext returns a context that is cancelled by Ctrl-C until stop is called
+func newInterruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
 }
 
-// displaySearchUIWithCursor displays the search interface with cursor position
-func displaySearchUIWithCursor(input string, cursorPos int, challenges []ChallengeResult) {
-	// Display input line with cursor visualization
-	if cursorPos >= len(input) {
-		fmt.Printf("input: %s█\r\n", input)
-	} else {
-		before := input[:cursorPos]
-		at := input[cursorPos : cursorPos+1]
-		after := input[cursorPos+1:]
-		fmt.Printf("input: %s\033[7m%s\033[0m%s\r\n", before, at, after)
+// exitIfInterrupted exits quietly with the conventional status 130 when err
+// comes from a load cancelled by Ctrl-C
+func exitIfInterrupted(err error) {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "Interrupted")
+		os.Exit(130)
+	}
+}
+
+// selectResultTemplate builds the output template from the --template,
+// --template-file, --template-header and --template-footer flags. It returns
+// nil when no template was given.
+func selectResultTemplate(text, file, header, footer string) (*resultTemplate, error) {
+	switch {
+	case text != "" && file != "":
+		return nil, fmt.Errorf("--template and --template-file cannot be used together")
+	case file != "":
+		return loadResultTemplate(file, header, footer)
+	case text != "":
+		return parseResultTemplate(text, header, footer)
+	case header != "" || footer != "":
+		return nil, fmt.Errorf("--template-header and --template-footer require --template or --template-file")
 	}
-	fmt.Print("\r\n") // Empty line
+	return nil, nil
+}
 
-	// Display results
-	if len(challenges) == 0 {
-		fmt.Print("No challenges found\r\n")
-	} else {
-		for _, challenge := range challenges {
-			if challenge.BranchName != "" {
-				fmt.Printf("- [%s] %s (tags: %s)\r\n",
-					challenge.BranchName,
-					challenge.Name,
-					strings.Join(challenge.Tags, ", "))
-			} else {
-				fmt.Printf("- %s (tags: %s)\r\n",
-					challenge.Name,
-					strings.Join(challenge.Tags, ", "))
-			}
+// isFlagSet reports whether a flag was given on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
-	}
+	})
+	return set
 }
 
-// filterChallengesByInput filters challenges by input string contained in tags
-func filterChallengesByInput(allChallenges []ChallengeResult, input string) []ChallengeResult {
-	if input == "" {
-		return allChallenges
+// filterChallengesByInput filters challenges by the query typed in the interactive prompt.
+// The parsed query is returned as well so that matches can be highlighted.
+func filterChallengesByInput(allChallenges []ChallengeResult, input string, opts QueryOptions) ([]ChallengeResult, *Query, error) {
+	if strings.TrimSpace(input) == "" {
+		return append([]ChallengeResult(nil), allChallenges...), &Query{}, nil
 	}
 
-	var results []ChallengeResult
-	searchTerm := strings.ToLower(strings.TrimSpace(input))
-
-	for _, challenge := range allChallenges {
-		// Check if the input string is contained in any of the challenge's tags
-		for _, tag := range challenge.Tags {
-			if strings.Contains(strings.ToLower(tag), searchTerm) {
-				results = append(results, challenge)
-				break // Found a match, no need to check other tags for this challenge
-			}
-		}
+	query, err := ParseQueryWithOptions(input, opts)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return results
+	return query.Filter(allChallenges), query, nil
 }
 
 // loadAllChallenges loads all challenges from all genres
@@ -267,16 +429,15 @@ func loadAllChallenges(genres []string) ([]ChallengeResult, error) {
 
 			challenge, err := loadChallenge(path)
 			if err != nil {
-				fmt.Printf("Warning: Failed to load %s: %v\n", path, err)
+				fmt.Fprintf(os.Stderr, "Warning: Failed to load %s: %v\n", path, err)
 				return nil
 			}
 
-			allChallenges = append(allChallenges, ChallengeResult{
-				Name:       challenge.Name,
-				Tags:       challenge.Tags,
-				FilePath:   path,
-				BranchName: "",
-			})
+			result := newChallengeResult(challenge, genre, path, "", "")
+			if info, err := d.Info(); err == nil {
+				result.ModTime = info.ModTime()
+			}
+			allChallenges = append(allChallenges, result)
 
 			return nil
 		})
@@ -289,17 +450,16 @@ func loadAllChallenges(genres []string) ([]ChallengeResult, error) {
 	return allChallenges, nil
 }
 
-// filterChallengesByTags filters challenges by the given search tags
-func filterChallengesByTags(allChallenges []ChallengeResult, searchTags []string) []ChallengeResult {
-	var results []ChallengeResult
-
-	for _, challenge := range allChallenges {
-		if hasMatchingTag(challenge.Tags, searchTags) {
-			results = append(results, challenge)
-		}
+// filterChallengesByTags filters challenges by the query formed from the command-line arguments.
+// Arguments are joined with spaces, so `searchall easy NOT beginner` and
+// `searchall 'easy NOT beginner'` are equivalent.
+func filterChallengesByTags(allChallenges []ChallengeResult, searchTags []string, opts QueryOptions) ([]ChallengeResult, error) {
+	query, err := ParseQueryWithOptions(strings.Join(searchTags, " "), opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return results
+	return query.Filter(allChallenges), nil
 }
 
 // findMatchingChallenges searches for challenges with matching tags (kept for backward compatibility)
@@ -309,7 +469,7 @@ func findMatchingChallenges(genres []string, searchTags []string) ([]ChallengeRe
 		return nil, err
 	}
 
-	return filterChallengesByTags(allChallenges, searchTags), nil
+	return filterChallengesByTags(allChallenges, searchTags, QueryOptions{})
 }
 
 // loadConfig loads and parses config.yaml
@@ -342,25 +502,13 @@ func loadChallenge(challengePath string) (*Challenge, error) {
 	return &challenge, nil
 }
 
-// hasMatchingTag checks if any of the search tags match the challenge tags
-func hasMatchingTag(challengeTags []string, searchTags []string) bool {
-	for _, searchTag := range searchTags {
-		for _, challengeTag := range challengeTags {
-			if strings.Contains(strings.ToLower(challengeTag), strings.ToLower(searchTag)) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-// displayMarkdownResults displays the results in markdown list format
-func displayMarkdownResults(results []ChallengeResult) {
+// displayMarkdownResults writes the results to w in markdown list format
+func displayMarkdownResults(w io.Writer, results []ChallengeResult) {
 	for _, result := range results {
 		if result.BranchName != "" {
-			fmt.Printf("- [%s] \"%s\"\n", result.BranchName, result.Name)
+			fmt.Fprintf(w, "- [%s] \"%s\"\n", result.BranchName, result.Name)
 		} else {
-			fmt.Printf("- \"%s\"\n", result.Name)
+			fmt.Fprintf(w, "- \"%s\"\n", result.Name)
 		}
 	}
 }
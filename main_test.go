@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -34,12 +37,54 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestQueryArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		query []string // Arguments left after parsing the flags
+	}{
+		{[]string{"-beginner"}, []string{"-beginner"}},
+		{[]string{"--", "-beginner"}, []string{"-beginner"}},
+		{[]string{"-beginner", "easy"}, []string{"-beginner", "easy"}},
+		{[]string{"-(easy OR medium)", "osint"}, []string{"-(easy OR medium)", "osint"}},
+		{[]string{"--all-branches", "-beginner"}, []string{"-beginner"}},
+		{[]string{"-all-branches", "-beginner"}, []string{"-beginner"}},
+		{[]string{"--match", "exact", "-beginner"}, []string{"-beginner"}},
+		{[]string{"--match=exact", "-beginner"}, []string{"-beginner"}},
+		{[]string{"easy", "-beginner"}, []string{"easy", "-beginner"}},
+		{[]string{"--all-branches", "easy"}, []string{"easy"}},
+	}
+
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("searchall", flag.ContinueOnError)
+		flags.Bool("all-branches", false, "")
+		flags.String("match", "", "")
+		if err := flags.Parse(queryArgs(flags, tt.args)); err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(flags.Args(), tt.query) {
+			t.Errorf("%q: expected query %q, got %q", tt.args, tt.query, flags.Args())
+		}
+	}
+
+	// Unknown double-dash flags are still errors
+	flags := flag.NewFlagSet("searchall", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(queryArgs(flags, []string{"--nosuch"})); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+}
+
 func TestLoadChallenge(t *testing.T) {
 	// Create temporary challenge file
 	tmpDir := t.TempDir()
 	challengePath := filepath.Join(tmpDir, "challenge.yml")
 
 	challengeContent := `name: "Test Challenge"
+description: "A test challenge"
+author: "Test Team"
+flag: "flag{test}"
+value: 100
 tags:
   - web
   - sql-injection
@@ -64,66 +109,13 @@ tags:
 	if !reflect.DeepEqual(challenge.Tags, expectedTags) {
 		t.Errorf("Expected tags %v, got %v", expectedTags, challenge.Tags)
 	}
-}
 
-func TestHasMatchingTag(t *testing.T) {
-	tests := []struct {
-		name          string
-		challengeTags []string
-		searchTags    []string
-		expected      bool
-	}{
-		{
-			name:          "Exact match",
-			challengeTags: []string{"web", "crypto", "beginner"},
-			searchTags:    []string{"web"},
-			expected:      true,
-		},
-		{
-			name:          "Partial match",
-			challengeTags: []string{"web-security", "crypto", "beginner"},
-			searchTags:    []string{"web"},
-			expected:      true,
-		},
-		{
-			name:          "Case insensitive match",
-			challengeTags: []string{"WEB", "CRYPTO", "BEGINNER"},
-			searchTags:    []string{"web"},
-			expected:      true,
-		},
-		{
-			name:          "Multiple search tags, one matches",
-			challengeTags: []string{"web", "crypto", "beginner"},
-			searchTags:    []string{"forensics", "web"},
-			expected:      true,
-		},
-		{
-			name:          "No match",
-			challengeTags: []string{"web", "crypto", "beginner"},
-			searchTags:    []string{"forensics", "reverse"},
-			expected:      false,
-		},
-		{
-			name:          "Empty challenge tags",
-			challengeTags: []string{},
-			searchTags:    []string{"web"},
-			expected:      false,
-		},
-		{
-			name:          "Empty search tags",
-			challengeTags: []string{"web"},
-			searchTags:    []string{},
-			expected:      false,
-		},
+	if challenge.Description != "A test challenge" || challenge.Author != "Test Team" || challenge.Flag != "flag{test}" {
+		t.Errorf("Unexpected description/author/flag: %q, %q, %q", challenge.Description, challenge.Author, challenge.Flag)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := hasMatchingTag(tt.challengeTags, tt.searchTags)
-			if result != tt.expected {
-				t.Errorf("Expected %v, got %v", tt.expected, result)
-			}
-		})
+	if challenge.Extra["value"] != 100 {
+		t.Errorf("Expected extra key 'value' to be 100, got %v", challenge.Extra["value"])
 	}
 }
 
@@ -221,3 +213,18 @@ tags:
 		t.Error("Should not find 'OSINT Challenge 1' when searching for 'web'")
 	}
 }
+
+func TestDisplayMarkdownResults(t *testing.T) {
+	results := []ChallengeResult{
+		{Name: "SQL Injection Basics"},
+		{Name: "Geolocation Challenge", BranchName: "feature/osint"},
+	}
+
+	var buf bytes.Buffer
+	displayMarkdownResults(&buf, results)
+
+	expected := "- \"SQL Injection Basics\"\n- [feature/osint] \"Geolocation Challenge\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MatchMode selects how a search term is compared with a field value
+type MatchMode int
+
+const (
+	MatchSubstring MatchMode = iota // value contains the term
+	MatchExact                      // value equals the term
+	MatchPrefix                     // value starts with the term
+	MatchGlob                       // value matches a shell-style pattern (* and ?)
+	MatchRegex                      // value matches a regular expression
+	MatchFuzzy                      // value contains the term's runes in order, tolerating typos
+)
+
+// matchModeNames maps the names accepted by --match and config.yaml to modes
+var matchModeNames = map[string]MatchMode{
+	"substring": MatchSubstring,
+	"exact":     MatchExact,
+	"prefix":    MatchPrefix,
+	"glob":      MatchGlob,
+	"regex":     MatchRegex,
+	"fuzzy":     MatchFuzzy,
+}
+
+// String returns the name of the match mode
+func (m MatchMode) String() string {
+	for name, mode := range matchModeNames {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("MatchMode(%d)", int(m))
+}
+
+// matchModeList lists the mode names in the order of the MatchMode
+// constants, e.g. "substring, exact, ... or fuzzy", for help and errors
+func matchModeList() string {
+	names := make([]string, 0, len(matchModeNames))
+	for name := range matchModeNames {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return matchModeNames[names[i]] < matchModeNames[names[j]] })
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// parseMatchMode converts a mode name into a MatchMode. An empty name selects substring matching.
+func parseMatchMode(name string) (MatchMode, error) {
+	if name == "" {
+		return MatchSubstring, nil
+	}
+	mode, ok := matchModeNames[strings.ToLower(name)]
+	if !ok {
+		return MatchSubstring, fmt.Errorf("unknown match mode %q (expected %s)", name, matchModeList())
+	}
+	return mode, nil
+}
+
+// valueMatcher reports whether a single field value matches a term and, if
+// so, the rune positions in the value that matched (used for highlighting)
+type valueMatcher func(value string) ([]int, bool)
+
+// compileMatcher builds a case-insensitive matcher for the term in the given mode.
+// Glob patterns use backslash to escape literal characters (see globEscape).
+func compileMatcher(mode MatchMode, text string) (valueMatcher, error) {
+	switch mode {
+	case MatchExact:
+		text = globUnescape(text)
+		return func(value string) ([]int, bool) {
+			if !strings.EqualFold(value, text) {
+				return nil, false
+			}
+			return runeRange(0, len([]rune(value))), true
+		}, nil
+	case MatchPrefix:
+		text = strings.ToLower(globUnescape(text))
+		return func(value string) ([]int, bool) {
+			if !strings.HasPrefix(strings.ToLower(value), text) {
+				return nil, false
+			}
+			return runeRange(0, len([]rune(text))), true
+		}, nil
+	case MatchGlob:
+		re, err := regexp.Compile("(?i)^" + globToRegexp(text) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", text, err)
+		}
+		return func(value string) ([]int, bool) {
+			if !re.MatchString(value) {
+				return nil, false
+			}
+			return runeRange(0, len([]rune(value))), true
+		}, nil
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", text, err)
+		}
+		return func(value string) ([]int, bool) {
+			loc := re.FindStringIndex(value)
+			if loc == nil {
+				return nil, false
+			}
+			start := utf8.RuneCountInString(value[:loc[0]])
+			return runeRange(start, start+utf8.RuneCountInString(value[loc[0]:loc[1]])), true
+		}, nil
+	case MatchFuzzy:
+		text = globUnescape(text)
+		return func(value string) ([]int, bool) {
+			_, positions, ok := fuzzyMatch(text, value)
+			return positions, ok
+		}, nil
+	default:
+		text = strings.ToLower(globUnescape(text))
+		return func(value string) ([]int, bool) {
+			lower := strings.ToLower(value)
+			idx := strings.Index(lower, text)
+			if idx < 0 {
+				return nil, false
+			}
+			start := utf8.RuneCountInString(lower[:idx])
+			return runeRange(start, start+utf8.RuneCountInString(text)), true
+		}, nil
+	}
+}
+
+// runeRange returns the positions start, start+1, ..., end-1
+func runeRange(start, end int) []int {
+	positions := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+// globEscape escapes glob metacharacters so they are matched literally
+func globEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r == '*' || r == '?' || r == '\\' {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// globUnescape removes the escaping added by globEscape
+func globUnescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// globToRegexp translates a glob pattern into an unanchored regular expression
+func globToRegexp(pattern string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			sb.WriteString(".*")
+		case r == '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMatchMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected MatchMode
+		wantErr  bool
+	}{
+		{input: "", expected: MatchSubstring},
+		{input: "substring", expected: MatchSubstring},
+		{input: "exact", expected: MatchExact},
+		{input: "Prefix", expected: MatchPrefix},
+		{input: "glob", expected: MatchGlob},
+		{input: "regex", expected: MatchRegex},
+		{input: "fuzzy", expected: MatchFuzzy},
+		{input: "levenshtein", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, err := parseMatchMode(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if mode != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, mode)
+			}
+		})
+	}
+}
+
+func TestMatchModeList(t *testing.T) {
+	expected := "substring, exact, prefix, glob, regex or fuzzy"
+	if got := matchModeList(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestCompileMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     MatchMode
+		text     string
+		value    string
+		expected bool
+	}{
+		{name: "Substring", mode: MatchSubstring, text: "web", value: "webandosint", expected: true},
+		{name: "Exact rejects superstring", mode: MatchExact, text: "web", value: "webandosint", expected: false},
+		{name: "Exact is case-insensitive", mode: MatchExact, text: "web", value: "WEB", expected: true},
+		{name: "Prefix", mode: MatchPrefix, text: "easy", value: "not-easy", expected: false},
+		{name: "Prefix match", mode: MatchPrefix, text: "geo", value: "geolocation", expected: true},
+		{name: "Glob star", mode: MatchGlob, text: "sql-*", value: "sql-injection", expected: true},
+		{name: "Glob is anchored", mode: MatchGlob, text: "sql", value: "sql-injection", expected: false},
+		{name: "Glob question mark", mode: MatchGlob, text: "we?", value: "web", expected: true},
+		{name: "Glob escaped star is literal", mode: MatchGlob, text: globEscape("a*") + "*", value: "abc", expected: false},
+		{name: "Glob escaped star matches star", mode: MatchGlob, text: globEscape("a*") + "*", value: "a*bc", expected: true},
+		{name: "Regex", mode: MatchRegex, text: "^geo.*n$", value: "Geolocation", expected: true},
+		{name: "Regex no match", mode: MatchRegex, text: "^web$", value: "webandosint", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := compileMatcher(tt.mode, tt.text)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, got := matches(tt.value); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompileMatcherPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     MatchMode
+		text     string
+		value    string
+		expected []int
+	}{
+		{name: "Substring", mode: MatchSubstring, text: "and", value: "webandosint", expected: []int{3, 4, 5}},
+		{name: "Substring after multibyte", mode: MatchSubstring, text: "web", value: "日本web", expected: []int{2, 3, 4}},
+		{name: "Exact", mode: MatchExact, text: "web", value: "Web", expected: []int{0, 1, 2}},
+		{name: "Prefix", mode: MatchPrefix, text: "ge", value: "geolocation", expected: []int{0, 1}},
+		{name: "Regex", mode: MatchRegex, text: "o.i", value: "osint", expected: []int{0, 1, 2}},
+		{name: "Fuzzy", mode: MatchFuzzy, text: "gln", value: "geolocation", expected: []int{0, 3, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := compileMatcher(tt.mode, tt.text)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			positions, ok := matches(tt.value)
+			if !ok {
+				t.Fatalf("Expected %q to match %q", tt.text, tt.value)
+			}
+			if !reflect.DeepEqual(positions, tt.expected) {
+				t.Errorf("Expected positions %v, got %v", tt.expected, positions)
+			}
+		})
+	}
+}
+
+func TestCompileMatcherInvalidRegex(t *testing.T) {
+	if _, err := compileMatcher(MatchRegex, "("); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
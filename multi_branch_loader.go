@@ -1,106 +1,411 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path"
+	"runtime"
 	"sort"
-
-	"gopkg.in/yaml.v3"
+	"sync"
+	"time"
 )
 
-// BranchPriority represents the priority of a branch for deduplication
+// BranchPriority represents the priority of a branch for deduplication; lower is preferred
 type BranchPriority int
 
+// Entries of the branch priority list standing for a branch determined at run time
 const (
-	PriorityMain    BranchPriority = 1
-	PriorityCurrent BranchPriority = 2
-	PriorityOther   BranchPriority = 3
+	PriorityDefault = "@default" // The default branch
+	PriorityCurrent = "@current" // The checked-out branch
 )
 
-// MultiBranchLoader loads challenges from all local branches and deduplicates them
+// defaultBranchPriority is the priority list used when config.yaml has none:
+// the default branch, then the current branch, then the others
+var defaultBranchPriority = []string{PriorityDefault, PriorityCurrent}
+
+// BranchConfig is the `branches:` section of config.yaml
+type BranchConfig struct {
+	Default  string   `yaml:"default"`  // Default branch; detected from origin/HEAD when empty
+	Priority []string `yaml:"priority"` // Ordered branch globs, @default and @current, preferred first
+}
+
+// validate checks the globs of the priority list
+func (c BranchConfig) validate() error {
+	for _, pattern := range c.Priority {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid branch pattern %q in branches.priority: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MultiBranchLoader loads challenges from all local branches, or the refs
+// selected by Refs, and deduplicates them
 type MultiBranchLoader struct {
-	CurrentBranch string
+	CurrentBranch   string
+	DefaultBranch   string            // Optional: detected from origin/HEAD, or main or master
+	Priority        []string          // Optional: ordered branch priority list, see BranchConfig
+	Refs            RefFilter         // Optional: defaults to the local branches
+	Git             GitRepository     // Optional: defaults to the repository in the current directory
+	Jobs            int               // Branches and files read at once; 0 means one per CPU
+	Index           *ChallengeIndex   // Optional: reuses the files read by earlier runs
+	DetectConflicts bool              // Compare the copies on lower-priority branches with the one kept
+	ModTimes        bool              // Look up the last commit time of each result, for sorting and output
+	Conflicts       []ChallengeDiff   // Copies that differ from the one kept, when DetectConflicts is set
+	AllCopies       bool              // Also parse the copies on lower-priority branches into Copies
+	Copies          []ChallengeResult // Every ref's copy of each challenge, when AllCopies is set
+}
+
+// branchListing is the challenge files of one genre on one ref
+type branchListing struct {
+	ref     Ref
+	genre   string
+	entries []TreeEntry
+	err     error // The genre directory exists but could not be listed
 }
 
-// LoadChallenges loads challenges from all local branches and returns deduplicated results
-// Optimized to parse only files from the highest priority branch
-func (m *MultiBranchLoader) LoadChallenges(genres []string) ([]ChallengeResult, error) {
-	branches, err := listLocalBranches()
+// parsedBlob is a challenge.yml parsed from its object ID
+type parsedBlob struct {
+	challenge *Challenge
+	readErr   error // The blob could not be read
+	parseErr  error // The blob is not a valid challenge.yml
+}
+
+// LoadChallenges loads challenges from the selected refs and returns deduplicated results.
+// Refs and genres are listed concurrently, then only the copy of each file from the
+// highest-priority ref is parsed, unless conflicts are detected. The result does not
+// depend on the order in which the work finishes.
+func (m *MultiBranchLoader) LoadChallenges(ctx context.Context, genres []string) ([]ChallengeResult, error) {
+	repo := repositoryOr(m.Git)
+	refs, err := repo.Refs()
 	if err != nil {
 		return nil, err
 	}
+	if m.DefaultBranch == "" {
+		m.DefaultBranch = detectDefaultBranch(repo, refs)
+	}
 
-	// Sort branches by priority (main -> current -> others)
-	sortedBranches := m.sortBranchesByPriority(branches)
-
-	// Track which file paths we've already processed
-	processedFiles := make(map[string]bool)
-	var results []ChallengeResult
+	// Sort refs by priority (default -> current -> others, unless configured)
+	sortedRefs := m.sortRefsByPriority(m.Refs.filter(refs))
 
-	// Process branches in priority order
-	for _, branch := range sortedBranches {
+	// List every genre on every ref; the slice keeps the priority order
+	var listings []branchListing
+	for _, ref := range sortedRefs {
 		for _, genre := range genres {
-			files, err := listChallengeFilesInBranch(branch, genre)
-			if err != nil {
-				// Genre might not exist in this branch, skip
+			listings = append(listings, branchListing{ref: ref, genre: genre})
+		}
+	}
+	err = forEachParallel(ctx, m.Jobs, len(listings), func(i int) {
+		// A genre missing from a ref has no entries
+		listings[i].entries, listings[i].err = m.Index.listing(repo, listings[i].ref.FullName, listings[i].genre)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, listing := range listings {
+		if listing.err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to list %s in branch %s: %v\n", listing.genre, listing.ref.Name, listing.err)
+		}
+	}
+
+	// Keep the copy of each path from the highest-priority ref, and the
+	// differing copies on other refs when looking for conflicts
+	type fileCopy struct {
+		listing *branchListing
+		entry   TreeEntry
+	}
+	kept := make(map[string]fileCopy)
+	var keptOrder []fileCopy
+	var others []fileCopy
+	var allCopies []fileCopy
+	var oids []string
+	oidIndex := make(map[string]int)
+	need := func(oid string) {
+		if _, ok := oidIndex[oid]; !ok {
+			oidIndex[oid] = len(oids)
+			oids = append(oids, oid)
+		}
+	}
+	// A local branch and its remote-tracking branches are the same line of
+	// work, so only the first copy of each line is compared
+	lines := make(map[string][]Ref)
+	onSeenLine := func(path string, ref Ref) bool {
+		for _, seen := range lines[path] {
+			if sameLine(seen, ref) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range listings {
+		for _, entry := range listings[i].entries {
+			c := fileCopy{listing: &listings[i], entry: entry}
+			if m.AllCopies {
+				allCopies = append(allCopies, c)
+				need(entry.OID)
+			}
+			if first, ok := kept[entry.Path]; ok {
+				if m.DetectConflicts && entry.OID != first.entry.OID && !onSeenLine(entry.Path, c.listing.ref) {
+					others = append(others, c)
+					need(entry.OID)
+				}
+				lines[entry.Path] = append(lines[entry.Path], c.listing.ref)
 				continue
 			}
+			kept[entry.Path] = c
+			keptOrder = append(keptOrder, c)
+			lines[entry.Path] = []Ref{c.listing.ref}
+			need(entry.OID)
+		}
+	}
 
-			for _, filePath := range files {
-				// Skip if we've already processed this file from a higher-priority branch
-				if processedFiles[filePath] {
-					continue
-				}
+	// Parse each distinct file once
+	parsed := make([]parsedBlob, len(oids))
+	err = forEachParallel(ctx, m.Jobs, len(oids), func(i int) {
+		parsed[i] = m.Index.challenge(repo, oids[i])
+	})
+	if err != nil {
+		return nil, err
+	}
 
-				// Mark as processed
-				processedFiles[filePath] = true
+	// parsedCopy returns the challenge of a copy, warning about invalid files
+	parsedCopy := func(c fileCopy) *Challenge {
+		blob := parsed[oidIndex[c.entry.OID]]
+		if blob.parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to parse %s in branch %s: %v\n", c.entry.Path, c.listing.ref.Name, blob.parseErr)
+		}
+		// Files that cannot be read are skipped silently
+		return blob.challenge
+	}
 
-				// Parse the challenge file
-				content, err := getFileContentFromBranch(branch, filePath)
-				if err != nil {
-					// File might not exist or be readable, skip
-					continue
-				}
+	var results []ChallengeResult
+	var resultRefs []Ref
+	keptChallenges := make(map[string]*Challenge)
+	for _, c := range keptOrder {
+		if challenge := parsedCopy(c); challenge != nil {
+			keptChallenges[c.entry.Path] = challenge
+			results = append(results, newChallengeResult(challenge, c.listing.genre, c.entry.Path, c.listing.ref.Name, c.listing.ref.FullName))
+			resultRefs = append(resultRefs, c.listing.ref)
+		}
+	}
 
-				var challenge Challenge
-				if err := yaml.Unmarshal(content, &challenge); err != nil {
-					fmt.Printf("Warning: Failed to parse %s in branch %s: %v\n", filePath, branch, err)
-					continue
-				}
+	// git log is needed for the times, so they are looked up once per ref
+	// here rather than whenever the results are sorted
+	if m.ModTimes {
+		if err := m.fillModTimes(ctx, repo, results, resultRefs); err != nil {
+			return nil, err
+		}
+	}
+	saveIndex(repo, m.Index)
 
-				results = append(results, ChallengeResult{
-					Name:       challenge.Name,
-					Tags:       challenge.Tags,
-					FilePath:   filePath,
-					BranchName: branch,
-				})
-			}
+	m.Conflicts = nil
+	for _, c := range others {
+		base, ok := keptChallenges[c.entry.Path]
+		if !ok {
+			continue
+		}
+		challenge := parsedCopy(c)
+		if challenge == nil {
+			continue
+		}
+		if fields := diffChallenges(base, challenge); len(fields) > 0 {
+			m.Conflicts = append(m.Conflicts, ChallengeDiff{
+				Path:   c.entry.Path,
+				Name:   base.Name,
+				Base:   kept[c.entry.Path].listing.ref.Name,
+				Branch: c.listing.ref.Name,
+				Status: DiffChanged,
+				Fields: fields,
+			})
+		}
+	}
+	sortChallengeDiffs(m.Conflicts)
+
+	// Copies of kept files were already parsed above, so only the others warn
+	m.Copies = nil
+	for _, c := range allCopies {
+		var challenge *Challenge
+		if kept[c.entry.Path] == c {
+			challenge = keptChallenges[c.entry.Path]
+		} else {
+			challenge = parsedCopy(c)
+		}
+		if challenge != nil {
+			m.Copies = append(m.Copies, newChallengeResult(challenge, c.listing.genre, c.entry.Path, c.listing.ref.Name, c.listing.ref.FullName))
 		}
 	}
 
 	return results, nil
 }
 
-// sortBranchesByPriority sorts branches by priority: main -> current -> others
+// fillModTimes sets the last commit time of each result, looking up the
+// files of each ref with a single git log. refs[i] is the ref of results[i].
+// Times that cannot be found stay unknown.
+func (m *MultiBranchLoader) fillModTimes(ctx context.Context, repo GitRepository, results []ChallengeResult, refs []Ref) error {
+	var order []Ref
+	paths := make(map[string][]string)
+	for i, ref := range refs {
+		if _, ok := paths[ref.FullName]; !ok {
+			order = append(order, ref)
+		}
+		paths[ref.FullName] = append(paths[ref.FullName], results[i].FilePath)
+	}
+
+	times := make([]map[string]time.Time, len(order))
+	err := forEachParallel(ctx, m.Jobs, len(order), func(i int) {
+		times[i], _ = m.Index.modTimes(repo, order[i].FullName, paths[order[i].FullName])
+	})
+	if err != nil {
+		return err
+	}
+
+	byRef := make(map[string]map[string]time.Time, len(order))
+	for i, ref := range order {
+		byRef[ref.FullName] = times[i]
+	}
+	for i := range results {
+		if modTime, ok := byRef[refs[i].FullName][results[i].FilePath]; ok {
+			results[i].ModTime = modTime
+		}
+	}
+	return nil
+}
+
+// forEachParallel calls fn with 0 to n-1 on up to jobs goroutines (one per
+// CPU when jobs is 0). Once ctx is cancelled no more calls are started, and
+// the context's error is returned after the running calls finish.
+func forEachParallel(ctx context.Context, jobs, n int, fn func(i int)) error {
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// sortBranchesByPriority sorts branches by priority: default -> current -> others (by name),
+// or as configured by Priority
 func (m *MultiBranchLoader) sortBranchesByPriority(branches []string) []string {
+	refs := make([]Ref, len(branches))
+	for i, branch := range branches {
+		refs[i] = Ref{Name: branch, Kind: RefLocal, Line: branch}
+	}
+
 	sorted := make([]string, len(branches))
-	copy(sorted, branches)
+	for i, ref := range m.sortRefsByPriority(refs) {
+		sorted[i] = ref.Name
+	}
+	return sorted
+}
+
+// refKindOrder puts a local branch before its remote-tracking branches
+var refKindOrder = map[RefKind]int{RefLocal: 0, RefRemote: 1, RefTag: 2}
+
+// sortRefsByPriority sorts refs by the priority of their line. A
+// remote-tracking branch has the priority of the local branch of the same
+// name and comes right after it; tags come among the other branches.
+func (m *MultiBranchLoader) sortRefsByPriority(refs []Ref) []Ref {
+	sorted := make([]Ref, len(refs))
+	copy(sorted, refs)
 
+	priority := func(ref Ref) BranchPriority {
+		if ref.Kind == RefTag {
+			return m.otherPriority()
+		}
+		return m.branchPriority(ref.Line)
+	}
+
+	// Refs of the same priority are ordered by name so that the choice is deterministic
 	sort.Slice(sorted, func(i, j int) bool {
-		priorityI := getBranchPriority(sorted[i], m.CurrentBranch)
-		priorityJ := getBranchPriority(sorted[j], m.CurrentBranch)
-		return priorityI < priorityJ
+		a, b := sorted[i], sorted[j]
+		if priorityA, priorityB := priority(a), priority(b); priorityA != priorityB {
+			return priorityA < priorityB
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Kind != b.Kind {
+			return refKindOrder[a.Kind] < refKindOrder[b.Kind]
+		}
+		return a.Name < b.Name
 	})
 
 	return sorted
 }
 
-// getBranchPriority returns the priority of a branch
-func getBranchPriority(branchName, currentBranch string) BranchPriority {
-	if branchName == "main" {
-		return PriorityMain
+// priorityList returns the configured priority list, or the default one
+func (m *MultiBranchLoader) priorityList() []string {
+	if m.Priority != nil {
+		return m.Priority
 	}
-	if branchName == currentBranch {
-		return PriorityCurrent
+	return defaultBranchPriority
+}
+
+// branchPriority returns the position of the first entry of the priority
+// list matching branchName. Branches matching no entry come after all of them.
+func (m *MultiBranchLoader) branchPriority(branchName string) BranchPriority {
+	for i, pattern := range m.priorityList() {
+		var matched bool
+		switch pattern {
+		case PriorityDefault:
+			matched = m.DefaultBranch != "" && branchName == m.DefaultBranch
+		case PriorityCurrent:
+			matched = m.CurrentBranch != "" && branchName == m.CurrentBranch
+		default:
+			matched, _ = path.Match(pattern, branchName)
+		}
+		if matched {
+			return BranchPriority(i)
+		}
+	}
+	return m.otherPriority()
+}
+
+// otherPriority is the priority of branches matching no entry of the priority list
+func (m *MultiBranchLoader) otherPriority() BranchPriority {
+	return BranchPriority(len(m.priorityList()))
+}
+
+// detectDefaultBranch returns the branch origin/HEAD points to, or else main
+// or master if it exists locally, or "" when there is no default branch
+func detectDefaultBranch(repo GitRepository, refs []Ref) string {
+	if branch, err := repo.DefaultBranch(); err == nil && branch != "" {
+		return branch
+	}
+	for _, candidate := range []string{"main", "master"} {
+		for _, ref := range refs {
+			if ref.Kind == RefLocal && ref.Name == candidate {
+				return candidate
+			}
+		}
 	}
-	return PriorityOther
+	return ""
 }
@@ -1,45 +1,103 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
-func TestGetBranchPriority(t *testing.T) {
+func TestBranchPriority(t *testing.T) {
 	tests := []struct {
 		name          string
 		branchName    string
+		defaultBranch string
 		currentBranch string
+		priority      []string
 		expected      BranchPriority
 	}{
 		{
-			name:          "main branch has highest priority",
+			name:          "default branch has highest priority",
 			branchName:    "main",
+			defaultBranch: "main",
 			currentBranch: "feature/test",
-			expected:      PriorityMain,
+			expected:      0,
 		},
 		{
 			name:          "current branch has second priority",
 			branchName:    "feature/test",
+			defaultBranch: "main",
 			currentBranch: "feature/test",
-			expected:      PriorityCurrent,
+			expected:      1,
 		},
 		{
 			name:          "other branch has lowest priority",
 			branchName:    "feature/other",
+			defaultBranch: "main",
 			currentBranch: "feature/test",
-			expected:      PriorityOther,
+			expected:      2,
 		},
 		{
-			name:          "main is always priority even if it's current",
+			name:          "default branch is always first even if it's current",
 			branchName:    "main",
+			defaultBranch: "main",
 			currentBranch: "main",
-			expected:      PriorityMain,
+			expected:      0,
+		},
+		{
+			name:          "main is not special when the default branch is master",
+			branchName:    "main",
+			defaultBranch: "master",
+			currentBranch: "feature/test",
+			expected:      2,
+		},
+		{
+			name:          "master as the default branch",
+			branchName:    "master",
+			defaultBranch: "master",
+			currentBranch: "feature/test",
+			expected:      0,
+		},
+		{
+			name:          "no default branch",
+			branchName:    "main",
+			currentBranch: "feature/test",
+			expected:      2,
+		},
+		{
+			name:          "priority list matches globs in order",
+			branchName:    "release/1.0",
+			defaultBranch: "develop",
+			currentBranch: "feature/test",
+			priority:      []string{"main", "release/*", PriorityDefault, PriorityCurrent},
+			expected:      1,
+		},
+		{
+			name:          "priority list places the default branch",
+			branchName:    "develop",
+			defaultBranch: "develop",
+			currentBranch: "feature/test",
+			priority:      []string{"main", "release/*", PriorityDefault, PriorityCurrent},
+			expected:      2,
+		},
+		{
+			name:          "current branch is not special unless listed",
+			branchName:    "feature/test",
+			defaultBranch: "develop",
+			currentBranch: "feature/test",
+			priority:      []string{"main", "release/*"},
+			expected:      2,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := getBranchPriority(tt.branchName, tt.currentBranch)
+			loader := &MultiBranchLoader{CurrentBranch: tt.currentBranch, DefaultBranch: tt.defaultBranch, Priority: tt.priority}
+			result := loader.branchPriority(tt.branchName)
 			if result != tt.expected {
 				t.Errorf("Expected priority %d, got %d", tt.expected, result)
 			}
@@ -48,7 +106,7 @@ func TestGetBranchPriority(t *testing.T) {
 }
 
 func TestSortBranchesByPriority(t *testing.T) {
-	loader := &MultiBranchLoader{CurrentBranch: "feat/test"}
+	loader := &MultiBranchLoader{CurrentBranch: "feat/test", DefaultBranch: "main"}
 
 	branches := []string{"feat/other", "main", "feat/another", "feat/test"}
 
@@ -80,14 +138,350 @@ func TestSortBranchesByPriority(t *testing.T) {
 }
 
 func TestSortBranchesByPriorityWithMainAsCurrent(t *testing.T) {
-	loader := &MultiBranchLoader{CurrentBranch: "main"}
+	loader := &MultiBranchLoader{CurrentBranch: "main", DefaultBranch: "main"}
 
 	branches := []string{"feat/test", "main", "feat/other"}
 
 	sorted := loader.sortBranchesByPriority(branches)
 
-	// main should still be first (PriorityMain takes precedence over PriorityCurrent)
+	// main should still be first (the default branch takes precedence over the current one)
 	if sorted[0] != "main" {
 		t.Errorf("Expected first branch to be 'main', got '%s'", sorted[0])
 	}
 }
+
+func TestSortBranchesByPriorityIsDeterministic(t *testing.T) {
+	loader := &MultiBranchLoader{CurrentBranch: "feat/b", DefaultBranch: "main"}
+	expected := []string{"main", "feat/b", "feat/a", "feat/c", "fix/z"}
+
+	for i := 0; i < 10; i++ {
+		branches := []string{"fix/z", "feat/c", "main", "feat/a", "feat/b"}
+		if i%2 == 1 {
+			branches = []string{"feat/a", "feat/b", "feat/c", "fix/z", "main"}
+		}
+		if sorted := loader.sortBranchesByPriority(branches); !reflect.DeepEqual(sorted, expected) {
+			t.Fatalf("Expected %v, got %v", expected, sorted)
+		}
+	}
+}
+
+func TestForEachParallel(t *testing.T) {
+	var calls [100]atomic.Int32
+	var running, peak atomic.Int32
+	err := forEachParallel(context.Background(), 4, len(calls), func(i int) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		calls[i].Add(1)
+		running.Add(-1)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range calls {
+		if n := calls[i].Load(); n != 1 {
+			t.Errorf("Expected index %d to be called once, got %d", i, n)
+		}
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("Expected at most 4 concurrent calls, got %d", p)
+	}
+
+	if err := forEachParallel(context.Background(), 0, 0, func(int) { t.Error("Unexpected call") }); err != nil {
+		t.Errorf("Unexpected error for no work: %v", err)
+	}
+}
+
+func TestForEachParallelCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	err := forEachParallel(ctx, 2, 1000, func(i int) {
+		if calls.Add(1) == 10 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if n := calls.Load(); n >= 1000 {
+		t.Errorf("Expected cancellation to stop the work early, got %d calls", n)
+	}
+}
+
+func TestMultiBranchLoaderConcurrentIsDeterministic(t *testing.T) {
+	// Every branch has its own copy of every challenge, so the branch
+	// priority alone decides which copy is kept
+	branches := make(map[string]map[string]string)
+	for _, branch := range []string{"main", "feat/current", "feat/a", "feat/b", "feat/c", "feat/d"} {
+		files := make(map[string]string)
+		for i := 0; i < 20; i++ {
+			files[fmt.Sprintf("web/chall_%02d/challenge.yml", i)] = fmt.Sprintf("name: Challenge %d on %s\n", i, branch)
+			files[fmt.Sprintf("osint/chall_%02d/challenge.yml", i)] = fmt.Sprintf("name: OSINT %d on %s\n", i, branch)
+		}
+		if branch != "main" {
+			files["web/only_"+branch+"/challenge.yml"] = "name: Only " + branch + "\n"
+		}
+		branches[branch] = files
+	}
+	repo := newFakeGitRepository("feat/current", branches)
+
+	load := func(jobs int) ([]ChallengeResult, []ChallengeDiff) {
+		loader := &MultiBranchLoader{CurrentBranch: "feat/current", Git: repo, Jobs: jobs, DetectConflicts: true}
+		results, err := loader.LoadChallenges(context.Background(), []string{"web", "osint"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return results, loader.Conflicts
+	}
+
+	sequential, sequentialConflicts := load(1)
+	if len(sequential) != 45 {
+		t.Fatalf("Expected 45 challenges, got %d", len(sequential))
+	}
+	for _, result := range sequential {
+		if strings.HasPrefix(result.FilePath, "web/chall_") && result.BranchName != "main" {
+			t.Errorf("Expected %s from main, got %s", result.FilePath, result.BranchName)
+		}
+	}
+	if len(sequentialConflicts) != 200 {
+		t.Errorf("Expected 200 conflicts, got %d", len(sequentialConflicts))
+	}
+
+	for i := 0; i < 5; i++ {
+		concurrent, concurrentConflicts := load(8)
+		if !reflect.DeepEqual(concurrent, sequential) {
+			t.Fatal("Expected concurrent loading to give the same results as sequential loading")
+		}
+		if !reflect.DeepEqual(concurrentConflicts, sequentialConflicts) {
+			t.Fatal("Expected concurrent loading to give the same conflicts as sequential loading")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	loader := &MultiBranchLoader{CurrentBranch: "feat/current", Git: repo, Jobs: 4}
+	if _, err := loader.LoadChallenges(ctx, []string{"web"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSortRefsByPriority(t *testing.T) {
+	loader := &MultiBranchLoader{CurrentBranch: "feat", DefaultBranch: "main"}
+	var refs []Ref
+	for _, fullName := range []string{
+		"refs/tags/v1",
+		"refs/remotes/origin/other",
+		"refs/remotes/origin/main",
+		"refs/heads/other",
+		"refs/remotes/origin/feat",
+		"refs/heads/feat",
+		"refs/remotes/upstream/main",
+		"refs/heads/main",
+	} {
+		ref, _ := newRef(fullName)
+		refs = append(refs, ref)
+	}
+
+	var got []string
+	for _, ref := range loader.sortRefsByPriority(refs) {
+		got = append(got, ref.Name)
+	}
+	expected := []string{"main", "origin/main", "upstream/main", "feat", "origin/feat", "other", "origin/other", "v1"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestMultiBranchLoaderWithRemoteRefs(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {
+			"web/a/challenge.yml": "name: A\nflag: flag{a}\n",
+		},
+		"feat": {
+			"web/a/challenge.yml": "name: A\nflag: flag{feat}\n",
+		},
+		"origin/main": {
+			"web/a/challenge.yml": "name: A\nflag: flag{old}\n",
+		},
+		"origin/feat": {
+			"web/a/challenge.yml": "name: A\nflag: flag{feat-pushed}\n",
+		},
+		"origin/chall-b": {
+			"web/b/challenge.yml": "name: B\n",
+		},
+		"tags/v1": {
+			"web/a/challenge.yml": "name: A\nflag: flag{v1}\n",
+		},
+	})
+
+	load := func(refs string) ([]string, []string) {
+		filter, err := parseRefFilter(refs, "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		loader := &MultiBranchLoader{CurrentBranch: "main", Git: repo, Refs: filter, DetectConflicts: true}
+		results, err := loader.LoadChallenges(context.Background(), []string{"web"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var challenges, conflicts []string
+		for _, result := range results {
+			challenges = append(challenges, result.Name+"@"+result.BranchName)
+		}
+		for _, conflict := range loader.Conflicts {
+			conflicts = append(conflicts, conflict.Base+"->"+conflict.Branch)
+		}
+		return challenges, conflicts
+	}
+
+	challenges, conflicts := load("")
+	if !reflect.DeepEqual(challenges, []string{"A@main"}) || !reflect.DeepEqual(conflicts, []string{"main->feat"}) {
+		t.Errorf("Expected only local branches, got %v and conflicts %v", challenges, conflicts)
+	}
+
+	// Remote-tracking branches of a line already compared are not conflicts
+	// of their own, so origin/main and origin/feat are not reported
+	challenges, conflicts = load("all")
+	if !reflect.DeepEqual(challenges, []string{"A@main", "B@origin/chall-b"}) {
+		t.Errorf("Expected the remote-only challenge, got %v", challenges)
+	}
+	if !reflect.DeepEqual(conflicts, []string{"main->feat", "main->v1"}) {
+		t.Errorf("Unexpected conflicts %v", conflicts)
+	}
+
+	// Without the local branch, the remote-tracking branch stands for the line
+	challenges, conflicts = load("remote")
+	if !reflect.DeepEqual(challenges, []string{"A@origin/main", "B@origin/chall-b"}) || !reflect.DeepEqual(conflicts, []string{"origin/main->origin/feat"}) {
+		t.Errorf("Unexpected results %v and conflicts %v", challenges, conflicts)
+	}
+
+	// Results keep the full ref name, so a tag is never read as a branch
+	filter, _ := parseRefFilter("tags", "")
+	loader := &MultiBranchLoader{CurrentBranch: "main", Git: repo, Refs: filter}
+	results, err := loader.LoadChallenges(context.Background(), []string{"web"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].BranchName != "v1" || results[0].Ref != "refs/tags/v1" {
+		t.Errorf("Expected A on refs/tags/v1 shown as v1, got %+v", results)
+	}
+}
+
+func TestSortBranchesWithPriorityList(t *testing.T) {
+	loader := &MultiBranchLoader{
+		CurrentBranch: "feat/b",
+		DefaultBranch: "develop",
+		Priority:      []string{"release/*", PriorityDefault, PriorityCurrent, "feat/*"},
+	}
+	branches := []string{"feat/a", "main", "develop", "release/2", "fix/z", "feat/b", "release/1"}
+	expected := []string{"release/1", "release/2", "develop", "feat/b", "feat/a", "fix/z", "main"}
+	if sorted := loader.sortBranchesByPriority(branches); !reflect.DeepEqual(sorted, expected) {
+		t.Errorf("Expected %v, got %v", expected, sorted)
+	}
+}
+
+func TestDetectDefaultBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		origin   string
+		branches []string
+		expected string
+	}{
+		{"origin/HEAD wins", "develop", []string{"main", "develop"}, "develop"},
+		{"main without origin/HEAD", "", []string{"feat", "main", "master"}, "main"},
+		{"master without origin/HEAD", "", []string{"feat", "master"}, "master"},
+		{"no default branch", "", []string{"feat", "origin/main"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branches := make(map[string]map[string]string)
+			for _, branch := range tt.branches {
+				branches[branch] = map[string]string{}
+			}
+			repo := newFakeGitRepository("feat", branches)
+			repo.defaultBranch = tt.origin
+			refs, _ := repo.Refs()
+			if got := detectDefaultBranch(repo, refs); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMultiBranchLoaderDetectsMaster(t *testing.T) {
+	// In a repository on master, master wins over other branches
+	repo := newFakeGitRepository("feat", map[string]map[string]string{
+		"master": {"web/a/challenge.yml": "name: A\nflag: flag{master}\n"},
+		"feat":   {"web/a/challenge.yml": "name: A\nflag: flag{feat}\n"},
+		"aaa":    {"web/a/challenge.yml": "name: A\nflag: flag{aaa}\n"},
+	})
+	loader := &MultiBranchLoader{CurrentBranch: "feat", Git: repo}
+	results, err := loader.LoadChallenges(context.Background(), []string{"web"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].BranchName != "master" {
+		t.Errorf("Expected the challenge from master, got %v", results)
+	}
+	if loader.DefaultBranch != "master" {
+		t.Errorf("Expected master to be detected, got %q", loader.DefaultBranch)
+	}
+}
+
+func TestMultiBranchLoaderModTimes(t *testing.T) {
+	repo := newFakeGitRepository("main", map[string]map[string]string{
+		"main": {"web/a/challenge.yml": "name: A\n", "web/b/challenge.yml": "name: B\n"},
+		"feat": {"web/a/challenge.yml": "name: A\nflag: flag{feat}\n", "web/c/challenge.yml": "name: C\n"},
+	})
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	repo.modTimes = map[string]time.Time{
+		"main:web/a/challenge.yml": day,
+		"feat:web/a/challenge.yml": day.AddDate(0, 0, 1),
+		"feat:web/c/challenge.yml": day.AddDate(0, 0, 2),
+	}
+
+	loader := &MultiBranchLoader{CurrentBranch: "main", DefaultBranch: "main", Git: repo}
+	if _, err := loader.LoadChallenges(context.Background(), []string{"web"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logs := repo.logs.Load(); logs != 0 {
+		t.Errorf("Expected no lookups without ModTimes, got %d", logs)
+	}
+
+	loader.ModTimes = true
+	results, err := loader.LoadChallenges(context.Background(), []string{"web"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Each result gets the time on the branch it was loaded from; b has no
+	// commit and stays unknown
+	got := make(map[string]time.Time)
+	for _, result := range results {
+		got[result.BranchName+":"+result.FilePath] = result.ModTime
+	}
+	expected := map[string]time.Time{
+		"main:web/a/challenge.yml": day,
+		"main:web/b/challenge.yml": {},
+		"feat:web/c/challenge.yml": day.AddDate(0, 0, 2),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if logs := repo.logs.Load(); logs != 2 {
+		t.Errorf("Expected one lookup per branch, got %d", logs)
+	}
+}
+
+func TestBranchConfigValidate(t *testing.T) {
+	if err := (BranchConfig{Priority: []string{"main", "release/*", PriorityCurrent}}).validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := (BranchConfig{Priority: []string{"release/["}}).validate(); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
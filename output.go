@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat selects how search results are written
+type OutputFormat string
+
+const (
+	FormatMarkdown      OutputFormat = "markdown"       // - "Name" list, the original output
+	FormatMarkdownTable OutputFormat = "markdown-table" // Markdown table with one column per field
+	FormatJSON          OutputFormat = "json"           // single JSON document
+	FormatJSONL         OutputFormat = "jsonl"          // one JSON object per line
+	FormatYAML          OutputFormat = "yaml"           // single YAML document
+	FormatCSV           OutputFormat = "csv"            // RFC 4180 CSV with a header row
+	FormatTSV           OutputFormat = "tsv"            // tab-separated values with a header row
+)
+
+// outputFormats lists the accepted formats in the order they are documented
+var outputFormats = []OutputFormat{FormatMarkdown, FormatMarkdownTable, FormatJSON, FormatJSONL, FormatYAML, FormatCSV, FormatTSV}
+
+// outputSchemaVersion is the version of the record layout written by the
+// structured formats. Fields and columns may be added at the end without a
+// version change; renaming, removing or reordering them requires a new version.
+const outputSchemaVersion = 1
+
+// parseOutputFormat converts a --format value into an OutputFormat. An empty value selects markdown.
+func parseOutputFormat(name string) (OutputFormat, error) {
+	if name == "" {
+		return FormatMarkdown, nil
+	}
+	for _, format := range outputFormats {
+		if string(format) == strings.ToLower(name) {
+			return format, nil
+		}
+	}
+
+	names := make([]string, len(outputFormats))
+	for i, format := range outputFormats {
+		names[i] = string(format)
+	}
+	return FormatMarkdown, fmt.Errorf("unknown output format %q (expected %s)", name, strings.Join(names, ", "))
+}
+
+// resultRecord is the stable, versioned representation of a ChallengeResult
+// in the structured output formats
+type resultRecord struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Description string                 `json:"description" yaml:"description"`
+	Author      string                 `json:"author" yaml:"author"`
+	Flag        *string                `json:"flag,omitempty" yaml:"flag,omitempty"` // Left out unless --include-flag is given
+	Tags        []string               `json:"tags" yaml:"tags"`
+	Genre       string                 `json:"genre" yaml:"genre"`
+	Path        string                 `json:"path" yaml:"path"`
+	Branch      string                 `json:"branch" yaml:"branch"`     // Empty unless --all-branches is used
+	Modified    string                 `json:"modified" yaml:"modified"` // RFC 3339, empty when unknown
+	Score       int                    `json:"score" yaml:"score"`       // Relevance for the query
+	Extra       map[string]interface{} `json:"extra" yaml:"extra"`       // Other keys in challenge.yml
+}
+
+// newResultRecord converts a result into its output record. Nil slices and
+// maps become empty ones so that consumers never see null. The flag is only
+// set with includeFlag, so that a left-out flag is not mistaken for an empty one.
+func newResultRecord(result ChallengeResult, includeFlag bool) resultRecord {
+	record := resultRecord{
+		Name:        result.Name,
+		Description: result.Description,
+		Author:      result.Author,
+		Tags:        result.Tags,
+		Genre:       result.Genre,
+		Path:        result.FilePath,
+		Branch:      result.BranchName,
+		Score:       result.Score,
+		Extra:       result.Extra,
+	}
+	if includeFlag {
+		record.Flag = &result.Flag
+	}
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	if record.Extra == nil {
+		record.Extra = map[string]interface{}{}
+	}
+	if !result.ModTime.IsZero() {
+		record.Modified = result.ModTime.UTC().Format(time.RFC3339)
+	}
+	return record
+}
+
+// resultDocument wraps the records of the single-document formats (json, yaml)
+type resultDocument struct {
+	Version int            `json:"version" yaml:"version"`
+	Results []resultRecord `json:"results" yaml:"results"`
+}
+
+// resultLine is a single jsonl record, carrying the schema version on every line
+type resultLine struct {
+	Version int `json:"version"`
+	resultRecord
+}
+
+// outputColumns are the columns of the tabular formats (csv, tsv, markdown-table), in order
+var outputColumns = []struct {
+	name  string
+	value func(resultRecord) string
+}{
+	{"name", func(r resultRecord) string { return r.Name }},
+	{"description", func(r resultRecord) string { return r.Description }},
+	{"author", func(r resultRecord) string { return r.Author }},
+	{"flag", func(r resultRecord) string {
+		if r.Flag == nil {
+			return ""
+		}
+		return *r.Flag
+	}},
+	{"tags", func(r resultRecord) string { return strings.Join(r.Tags, ",") }},
+	{"genre", func(r resultRecord) string { return r.Genre }},
+	{"path", func(r resultRecord) string { return r.Path }},
+	{"branch", func(r resultRecord) string { return r.Branch }},
+	{"modified", func(r resultRecord) string { return r.Modified }},
+	{"score", func(r resultRecord) string { return strconv.Itoa(r.Score) }},
+	{"extra", func(r resultRecord) string {
+		if len(r.Extra) == 0 {
+			return ""
+		}
+		data, err := json.Marshal(r.Extra)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}},
+}
+
+// outputResults writes the results with the user's template if one was
+// given, or in the selected output format otherwise. Both leave the flags out
+// unless --include-flag is given, so that piping the results into other tools
+// or CI logs does not leak them.
+func outputResults(w io.Writer, results []ChallengeResult, opts SearchOptions) error {
+	if !opts.IncludeFlag {
+		results = withoutFlags(results)
+	}
+	if opts.Template != nil {
+		return opts.Template.execute(w, results)
+	}
+	return writeResults(w, results, opts.Format, opts.IncludeFlag)
+}
+
+// withoutFlags returns a copy of results with the flags cleared
+func withoutFlags(results []ChallengeResult) []ChallengeResult {
+	cleared := make([]ChallengeResult, len(results))
+	for i, result := range results {
+		result.Flag = ""
+		cleared[i] = result
+	}
+	return cleared
+}
+
+// writeResults writes the results to w in the given format. The flags are
+// only written with includeFlag.
+func writeResults(w io.Writer, results []ChallengeResult, format OutputFormat, includeFlag bool) error {
+	records := make([]resultRecord, len(results))
+	for i, result := range results {
+		records[i] = newResultRecord(result, includeFlag)
+	}
+
+	switch format {
+	case FormatMarkdown:
+		displayMarkdownResults(w, results)
+		return nil
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resultDocument{Version: outputSchemaVersion, Results: records})
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, record := range records {
+			if err := encoder.Encode(resultLine{Version: outputSchemaVersion, resultRecord: record}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(resultDocument{Version: outputSchemaVersion, Results: records}); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatCSV:
+		return writeCSV(w, records)
+	case FormatTSV:
+		return writeTSV(w, records)
+	case FormatMarkdownTable:
+		return writeMarkdownTable(w, records)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// writeCSV writes the records as RFC 4180 CSV with a header row
+func writeCSV(w io.Writer, records []resultRecord) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(outputColumns))
+	for i, column := range outputColumns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := make([]string, len(outputColumns))
+		for i, column := range outputColumns {
+			row[i] = column.value(record)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// tsvEscaper escapes the characters that cannot appear in a TSV field
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// writeTSV writes the records as tab-separated values with a header row.
+// Backslashes, tabs and line breaks in values are escaped as \\, \t, \n and \r.
+func writeTSV(w io.Writer, records []resultRecord) error {
+	header := make([]string, len(outputColumns))
+	for i, column := range outputColumns {
+		header[i] = column.name
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := make([]string, len(outputColumns))
+		for i, column := range outputColumns {
+			row[i] = tsvEscaper.Replace(column.value(record))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownCellEscaper keeps a value inside a single Markdown table cell
+var markdownCellEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+// writeMarkdownTable writes the records as a GitHub-flavored Markdown table
+func writeMarkdownTable(w io.Writer, records []resultRecord) error {
+	header := make([]string, len(outputColumns))
+	separator := make([]string, len(outputColumns))
+	for i, column := range outputColumns {
+		header[i] = column.name
+		separator[i] = "---"
+	}
+	if _, err := fmt.Fprintf(w, "| %s |\n| %s |\n", strings.Join(header, " | "), strings.Join(separator, " | ")); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := make([]string, len(outputColumns))
+		for i, column := range outputColumns {
+			row[i] = markdownCellEscaper.Replace(strings.TrimSpace(column.value(record)))
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
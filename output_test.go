@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func outputTestResults() []ChallengeResult {
+	return []ChallengeResult{
+		{
+			Name:        "Geolocation Challenge",
+			Description: "Find the location\nfrom | metadata",
+			Author:      "OSINT Team",
+			Flag:        "flag{geo}",
+			Tags:        []string{"osint", "geolocation"},
+			Extra:       map[string]interface{}{"value": 100},
+			Genre:       "osint",
+			FilePath:    "osint/chall_2/challenge.yml",
+			BranchName:  "feature/osint",
+			ModTime:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Score:       45,
+		},
+		{Name: "Bare Challenge", Genre: "web", FilePath: "web/chall_1/challenge.yml"},
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    OutputFormat
+		wantErr bool
+	}{
+		{"", FormatMarkdown, false},
+		{"markdown", FormatMarkdown, false},
+		{"JSON", FormatJSON, false},
+		{"jsonl", FormatJSONL, false},
+		{"yaml", FormatYAML, false},
+		{"csv", FormatCSV, false},
+		{"tsv", FormatTSV, false},
+		{"markdown-table", FormatMarkdownTable, false},
+		{"xml", FormatMarkdown, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOutputFormat(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOutputFormat(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseOutputFormat(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatJSON, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Version int                      `json:"version"`
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Version != outputSchemaVersion || len(doc.Results) != 2 {
+		t.Fatalf("Expected version %d with 2 results, got %+v", outputSchemaVersion, doc)
+	}
+
+	first := doc.Results[0]
+	for field, want := range map[string]interface{}{
+		"name":     "Geolocation Challenge",
+		"path":     "osint/chall_2/challenge.yml",
+		"branch":   "feature/osint",
+		"genre":    "osint",
+		"modified": "2024-05-01T12:00:00Z",
+		"score":    float64(45),
+		"tags":     []interface{}{"osint", "geolocation"},
+		"extra":    map[string]interface{}{"value": float64(100)},
+	} {
+		if !reflect.DeepEqual(first[field], want) {
+			t.Errorf("Field %q = %#v, want %#v", field, first[field], want)
+		}
+	}
+
+	// Missing values are empty, never null
+	second := doc.Results[1]
+	if !reflect.DeepEqual(second["tags"], []interface{}{}) || !reflect.DeepEqual(second["extra"], map[string]interface{}{}) {
+		t.Errorf("Expected empty tags and extra, got %v and %v", second["tags"], second["extra"])
+	}
+	if second["modified"] != "" {
+		t.Errorf("Expected empty modified time, got %v", second["modified"])
+	}
+}
+
+func TestWriteResultsJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatJSONL, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per result, got %d:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Line is not valid JSON: %v\n%s", err, line)
+		}
+		if record["version"] != float64(outputSchemaVersion) {
+			t.Errorf("Expected version on every line, got %v", record["version"])
+		}
+		if _, ok := record["name"]; !ok {
+			t.Errorf("Expected fields to be inlined, got %v", record)
+		}
+	}
+}
+
+func TestWriteResultsYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatYAML, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc resultDocument
+	if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid YAML: %v\n%s", err, buf.String())
+	}
+	if doc.Version != outputSchemaVersion || len(doc.Results) != 2 {
+		t.Fatalf("Expected version %d with 2 results, got %+v", outputSchemaVersion, doc)
+	}
+	if doc.Results[0].Description != "Find the location\nfrom | metadata" {
+		t.Errorf("Expected multi-line description to survive, got %q", doc.Results[0].Description)
+	}
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatCSV, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	expectedHeader := []string{"name", "description", "author", "flag", "tags", "genre", "path", "branch", "modified", "score", "extra"}
+	if !reflect.DeepEqual(rows[0], expectedHeader) {
+		t.Errorf("Expected header %v, got %v", expectedHeader, rows[0])
+	}
+	expectedRow := []string{
+		"Geolocation Challenge", "Find the location\nfrom | metadata", "OSINT Team", "flag{geo}",
+		"osint,geolocation", "osint", "osint/chall_2/challenge.yml", "feature/osint",
+		"2024-05-01T12:00:00Z", "45", `{"value":100}`,
+	}
+	if !reflect.DeepEqual(rows[1], expectedRow) {
+		t.Errorf("Expected row %v, got %v", expectedRow, rows[1])
+	}
+}
+
+func TestWriteResultsTSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatTSV, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d lines:\n%s", len(lines), buf.String())
+	}
+	fields := strings.Split(lines[1], "\t")
+	if len(fields) != len(outputColumns) {
+		t.Fatalf("Expected %d fields, got %d: %q", len(outputColumns), len(fields), lines[1])
+	}
+	if fields[1] != `Find the location\nfrom | metadata` {
+		t.Errorf("Expected escaped line break, got %q", fields[1])
+	}
+}
+
+func TestWriteResultsMarkdownTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatMarkdownTable, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, separator and 2 rows, got %d lines:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "| name | description |") {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if !strings.Contains(lines[2], `| Find the location<br>from \| metadata |`) {
+		t.Errorf("Expected escaped description cell, got %q", lines[2])
+	}
+}
+
+func TestWriteResultsMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputTestResults(), FormatMarkdown, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "- [feature/osint] \"Geolocation Challenge\"\n- \"Bare Challenge\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestOutputResultsFlag(t *testing.T) {
+	for _, includeFlag := range []bool{false, true} {
+		var buf bytes.Buffer
+		opts := SearchOptions{Format: FormatJSONL, IncludeFlag: includeFlag}
+		if err := outputResults(&buf, outputTestResults(), opts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := strings.Contains(buf.String(), "flag{geo}"); got != includeFlag {
+			t.Errorf("IncludeFlag %v: expected the flag to be written %v, got:\n%s", includeFlag, includeFlag, buf.String())
+		}
+		// A left-out flag has no field, so it cannot be taken for an empty flag
+		if got := strings.Contains(buf.String(), `"flag":""`); got != includeFlag {
+			t.Errorf("IncludeFlag %v: expected the empty flag field %v, got:\n%s", includeFlag, includeFlag, buf.String())
+		}
+
+		// Templates get the same flags as the formats
+		tmpl, err := parseResultTemplate("{{.Name}}={{.Flag}}\n", "", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		buf.Reset()
+		opts.Template = tmpl
+		if err := outputResults(&buf, outputTestResults(), opts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := strings.Contains(buf.String(), "flag{geo}"); got != includeFlag {
+			t.Errorf("IncludeFlag %v: expected the template to get the flag %v, got:\n%s", includeFlag, includeFlag, buf.String())
+		}
+	}
+
+	// The results themselves keep their flags
+	results := outputTestResults()
+	withoutFlags(results)
+	if results[0].Flag != "flag{geo}" {
+		t.Error("Expected withoutFlags to copy the results")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// challengePreview holds the details shown in the preview pane
+type challengePreview struct {
+	challenge   *Challenge
+	publicFiles []string // Paths relative to the challenge directory
+	err         error    // Why challenge.yml could not be read, if it could not
+}
+
+// previewKey identifies a challenge across branches for caching previews
+func previewKey(result ChallengeResult) string {
+	return result.Ref + ":" + result.FilePath
+}
+
+// loadChallengePreview reads a challenge.yml and lists its public/ directory.
+// Results found on a branch are read through git so that challenges that are
+// not checked out can be previewed too.
+func loadChallengePreview(result ChallengeResult) *challengePreview {
+	preview := &challengePreview{}
+
+	if result.Ref == "" {
+		challenge, err := loadChallenge(result.FilePath)
+		if err != nil {
+			preview.err = err
+			return preview
+		}
+		preview.challenge = challenge
+		preview.publicFiles = listPublicFiles(filepath.Dir(result.FilePath))
+		return preview
+	}
+
+	content, err := getFileContentFromBranch(result.Ref, result.FilePath)
+	if err != nil {
+		preview.err = err
+		return preview
+	}
+
+	var challenge Challenge
+	if err := yaml.Unmarshal(content, &challenge); err != nil {
+		preview.err = fmt.Errorf("failed to parse challenge file: %w", err)
+		return preview
+	}
+	preview.challenge = &challenge
+
+	dir := path.Dir(result.FilePath)
+	if files, err := listFilesInBranch(result.Ref, path.Join(dir, "public")); err == nil {
+		for _, file := range files {
+			preview.publicFiles = append(preview.publicFiles, path.Join("public", file))
+		}
+	}
+	return preview
+}
+
+// listPublicFiles lists the files under dir/public in the working tree,
+// relative to dir
+func listPublicFiles(dir string) []string {
+	var files []string
+	_ = filepath.WalkDir(filepath.Join(dir, "public"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// previewLines renders the preview of a challenge as lines no wider than width
+func previewLines(result ChallengeResult, preview *challengePreview, width int) []string {
+	var lines []string
+	add := func(label, value string) {
+		lines = append(lines, wrapText(label+value, width)...)
+	}
+
+	add("Name: ", result.Name)
+	add("Path: ", result.FilePath)
+	if result.BranchName != "" {
+		add("Branch: ", result.BranchName)
+	}
+
+	if preview == nil {
+		return lines
+	}
+	if preview.err != nil {
+		add("Error: ", preview.err.Error())
+		return lines
+	}
+
+	challenge := preview.challenge
+	if challenge.Author != "" {
+		add("Author: ", challenge.Author)
+	}
+	add("Tags: ", strings.Join(challenge.Tags, ", "))
+
+	if challenge.Description != "" {
+		lines = append(lines, "", "Description:")
+		for _, paragraph := range strings.Split(strings.TrimSpace(challenge.Description), "\n") {
+			for _, line := range wrapText(paragraph, width-2) {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+
+	lines = append(lines, "", "Public files:")
+	if len(preview.publicFiles) == 0 {
+		lines = append(lines, "  (none)")
+	}
+	for _, file := range preview.publicFiles {
+		lines = append(lines, "  "+truncateToWidth(file, width-2))
+	}
+
+	return lines
+}
+
+// wrapText breaks s into lines of at most width display columns, preferring
+// to break at spaces. Text without spaces (e.g. Japanese) is broken between runes.
+func wrapText(s string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	if stringWidth(s) <= width {
+		return []string{s}
+	}
+
+	var lines []string
+	var line []rune
+	lineWidth := 0
+	lastSpace := -1 // Index in line of the last space, for word wrapping
+
+	for _, r := range s {
+		rw := runeWidth(r)
+		if lineWidth+rw > width {
+			if lastSpace > 0 && !unicode.IsSpace(r) {
+				// Move the partial word to the next line
+				lines = append(lines, string(line[:lastSpace]))
+				line = append([]rune(nil), line[lastSpace+1:]...)
+			} else {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			lineWidth = stringWidth(string(line))
+			lastSpace = -1
+			if unicode.IsSpace(r) {
+				continue
+			}
+		}
+		if unicode.IsSpace(r) {
+			lastSpace = len(line)
+		}
+		line = append(line, r)
+		lineWidth += rw
+	}
+
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadChallengePreviewFromFileSystem(t *testing.T) {
+	tmpDir := t.TempDir()
+	challDir := filepath.Join(tmpDir, "web", "chall_1")
+	if err := os.MkdirAll(filepath.Join(challDir, "public", "img"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	challengeContent := `name: "Preview Test"
+description: "Find the flag"
+author: "Test Team"
+tags:
+  - web`
+	challengePath := filepath.Join(challDir, "challenge.yml")
+	if err := os.WriteFile(challengePath, []byte(challengeContent), 0644); err != nil {
+		t.Fatalf("Failed to create test challenge: %v", err)
+	}
+	for _, name := range []string{"public/b.txt", "public/img/a.png"} {
+		if err := os.WriteFile(filepath.Join(challDir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create public file: %v", err)
+		}
+	}
+
+	preview := loadChallengePreview(ChallengeResult{Name: "Preview Test", FilePath: challengePath})
+	if preview.err != nil {
+		t.Fatalf("Unexpected error: %v", preview.err)
+	}
+	if preview.challenge.Author != "Test Team" {
+		t.Errorf("Expected author 'Test Team', got %q", preview.challenge.Author)
+	}
+
+	expected := []string{"public/b.txt", "public/img/a.png"}
+	if !reflect.DeepEqual(preview.publicFiles, expected) {
+		t.Errorf("Expected public files %v, got %v", expected, preview.publicFiles)
+	}
+}
+
+func TestLoadChallengePreviewFromBranch(t *testing.T) {
+	currentBranch, err := getCurrentBranch()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+
+	preview := loadChallengePreview(ChallengeResult{FilePath: "osint/chall_1/challenge.yml", BranchName: currentBranch, Ref: "refs/heads/" + currentBranch})
+	if preview.err != nil {
+		t.Fatalf("Unexpected error: %v", preview.err)
+	}
+	if preview.challenge.Name != "Social Media Investigation" {
+		t.Errorf("Expected 'Social Media Investigation', got %q", preview.challenge.Name)
+	}
+	if !reflect.DeepEqual(preview.publicFiles, []string{"public/sample_file.txt"}) {
+		t.Errorf("Expected public/sample_file.txt, got %v", preview.publicFiles)
+	}
+}
+
+func TestPreviewLines(t *testing.T) {
+	result := ChallengeResult{Name: "Geolocation Challenge", FilePath: "osint/chall_2/challenge.yml"}
+	preview := &challengePreview{
+		challenge: &Challenge{
+			Description: "Find the location from image metadata",
+			Author:      "OSINT Team",
+			Tags:        []string{"easy", "osint"},
+		},
+		publicFiles: []string{"public/photo.jpg"},
+	}
+
+	lines := previewLines(result, preview, 30)
+	text := strings.Join(lines, "\n")
+	for _, want := range []string{"Author: OSINT Team", "Tags: easy, osint", "  public/photo.jpg", "Description:"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", want, text)
+		}
+	}
+	for _, line := range lines {
+		if stringWidth(line) > 30 {
+			t.Errorf("Line exceeds width: %q", line)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected []string
+	}{
+		{name: "Fits", input: "easy osint", width: 10, expected: []string{"easy osint"}},
+		{name: "Word wrap", input: "find the location", width: 10, expected: []string{"find the", "location"}},
+		{name: "Long word", input: "geolocation", width: 5, expected: []string{"geolo", "catio", "n"}},
+		{name: "Wide runes", input: "画像の撮影場所", width: 6, expected: []string{"画像の", "撮影場", "所"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.input, tt.width); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
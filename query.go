@@ -0,0 +1,858 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query is a parsed search expression that can be evaluated against challenges.
+//
+// Syntax:
+//
+//	easy osint          both terms must match (implicit AND)
+//	easy AND osint      explicit AND
+//	web OR osint        either term matches
+//	NOT beginner        term must not match
+//	-beginner           shorthand for NOT beginner
+//	(web OR osint) easy parentheses group sub-expressions
+//	"social media"      quoted phrase, matched as a single term
+//	author:"OSINT Team" field-qualified term
+//	=web                exact match
+//	^web                prefix match
+//	~web                substring match
+//	name:geo*           glob match (* and ?); geo* is a prefix match
+//	/^geo.*n$/          regular expression
+//
+// Bare terms match tags. Field-qualified terms match the named challenge.yml
+// field: name, desc (description), author, flag, tag (tags), path and branch.
+// Any other field name is looked up among the remaining keys of challenge.yml.
+//
+// Terms without an explicit mode marker use QueryOptions.DefaultMode when they
+// match tags and substring matching for every other field. When the default
+// mode is regex, tag terms are taken as regular expressions verbatim: =, ^, ~,
+// *, ? and backslashes keep their regex meaning, and parentheses that are
+// balanced within the term belong to it. All comparisons are
+// case-insensitive. Tag terms found in QueryOptions.Aliases also match their
+// synonyms, and parent tags in QueryOptions.Taxonomy match their descendants.
+//
+// Operators are case-sensitive so that lowercase tags such as "not" can still
+// be searched. An empty query matches every challenge.
+type Query struct {
+	root  queryNode
+	terms []*termNode // Terms that are not negated, used for highlighting
+}
+
+// QueryOptions controls how a query is parsed
+type QueryOptions struct {
+	DefaultMode MatchMode           // Match mode for tag terms without an explicit marker
+	Aliases     map[string][]string // Tag synonyms, built by newAliasTable
+	Taxonomy    *Taxonomy           // Parent tags also match their descendants
+}
+
+// expandTag returns the tags a tag term stands for: the term, its aliases
+// and the descendants of each in the taxonomy. It returns nil when the term
+// has no expansion.
+func (o QueryOptions) expandTag(text string) []string {
+	names := o.Aliases[strings.ToLower(text)]
+	if names == nil {
+		names = []string{text}
+	}
+
+	var expanded []string
+	for _, name := range names {
+		for _, tag := range append([]string{name}, o.Taxonomy.descendants(name)...) {
+			if !containsFold(expanded, tag) {
+				expanded = append(expanded, tag)
+			}
+		}
+	}
+	if len(expanded) == 1 {
+		return nil
+	}
+	return expanded
+}
+
+// queryNode is a node of the parsed query tree
+type queryNode interface {
+	match(c *ChallengeResult) bool
+	// score rates how well a challenge matches; only meaningful when match is true
+	score(c *ChallengeResult) int
+}
+
+// Relevance points awarded per matched term
+const (
+	scoreSubstringHit = 10 // term found inside a value
+	scorePrefixHit    = 20 // value starts with the term
+	scoreExactHit     = 30 // value equals the term
+	scoreNameBonus    = 15 // tag term also appears in the challenge name
+	// Fuzzy-only hits score between 1 and scoreSubstringHit-1 (see fuzzyHitScore)
+)
+
+// andNode matches when every child matches
+type andNode struct {
+	children []queryNode
+}
+
+func (n *andNode) match(c *ChallengeResult) bool {
+	for _, child := range n.children {
+		if !child.match(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *andNode) score(c *ChallengeResult) int {
+	total := 0
+	for _, child := range n.children {
+		total += child.score(c)
+	}
+	return total
+}
+
+// orNode matches when any child matches
+type orNode struct {
+	children []queryNode
+}
+
+func (n *orNode) match(c *ChallengeResult) bool {
+	for _, child := range n.children {
+		if child.match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// score sums the matching alternatives, so challenges that satisfy more of
+// them rank higher
+func (n *orNode) score(c *ChallengeResult) int {
+	total := 0
+	for _, child := range n.children {
+		if child.match(c) {
+			total += child.score(c)
+		}
+	}
+	return total
+}
+
+// notNode negates its child
+type notNode struct {
+	child queryNode
+}
+
+func (n *notNode) match(c *ChallengeResult) bool {
+	return !n.child.match(c)
+}
+
+func (n *notNode) score(c *ChallengeResult) int {
+	return 0
+}
+
+// synonymNode matches a tag term expanded through the alias table: the
+// challenge matches if any synonym does, and scores by the best synonym
+type synonymNode struct {
+	children []*termNode
+}
+
+func (n *synonymNode) match(c *ChallengeResult) bool {
+	for _, child := range n.children {
+		if child.match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *synonymNode) score(c *ChallengeResult) int {
+	best := 0
+	for _, child := range n.children {
+		if child.match(c) {
+			best = max(best, child.score(c))
+		}
+	}
+	return best
+}
+
+// termNode matches a single search term against a challenge field (tags by default)
+type termNode struct {
+	field   string // Lower-cased field name, empty for bare terms
+	text    string
+	mode    MatchMode
+	matches valueMatcher
+}
+
+func (n *termNode) match(c *ChallengeResult) bool {
+	for _, value := range challengeFieldValues(c, n.field) {
+		if _, ok := n.matches(value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// score rates the best hit among the field values: exact > prefix > substring
+// > fuzzy. Tag terms earn a bonus when the challenge name also contains them.
+func (n *termNode) score(c *ChallengeResult) int {
+	text := strings.ToLower(globUnescape(n.text))
+	best := 0
+	for _, value := range challengeFieldValues(c, n.field) {
+		matched, ok := n.matches(value)
+		if !ok {
+			continue
+		}
+		// Patterns are scored by what they matched, not by their text
+		switch n.mode {
+		case MatchGlob:
+			best = max(best, globHitScore(n.text, value))
+			continue
+		case MatchRegex:
+			best = max(best, regexHitScore(matched, value))
+			continue
+		}
+		value = strings.ToLower(value)
+		switch {
+		case value == text:
+			best = max(best, scoreExactHit)
+		case strings.HasPrefix(value, text):
+			best = max(best, scorePrefixHit)
+		case n.mode == MatchFuzzy && !strings.Contains(value, text):
+			best = max(best, fuzzyHitScore(text, value))
+		default:
+			best = max(best, scoreSubstringHit)
+		}
+	}
+
+	if best > 0 && isTagField(n.field) && text != "" && strings.Contains(strings.ToLower(c.Name), text) {
+		best += scoreNameBonus
+	}
+	return best
+}
+
+// globHitScore rates a value matched by a glob pattern. Glob patterns are
+// anchored, so the hit is exact when every * matched nothing, a prefix when
+// the pattern does not start with *, and a substring otherwise.
+func globHitScore(pattern, value string) int {
+	fixed := 0 // Runes of the value matched by anything but *
+	leadingStar := false
+	escaped := false
+	for i, r := range []rune(pattern) {
+		switch {
+		case escaped:
+			escaped = false
+			fixed++
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			leadingStar = leadingStar || i == 0
+		default:
+			fixed++
+		}
+	}
+
+	switch {
+	case utf8.RuneCountInString(value) == fixed:
+		return scoreExactHit
+	case !leadingStar:
+		return scorePrefixHit
+	default:
+		return scoreSubstringHit
+	}
+}
+
+// regexHitScore rates a value from the rune positions a regex matched: exact
+// when the match covers the whole value, a prefix when it starts at 0
+func regexHitScore(matched []int, value string) int {
+	switch {
+	case len(matched) == 0 || matched[0] != 0:
+		return scoreSubstringHit
+	case len(matched) == utf8.RuneCountInString(value):
+		return scoreExactHit
+	default:
+		return scorePrefixHit
+	}
+}
+
+// fuzzyHitScore scales a fuzzy match score into the range below scoreSubstringHit
+func fuzzyHitScore(text, value string) int {
+	score, _, _ := fuzzyMatch(text, value)
+	perfect := len([]rune(text)) * (fuzzyScoreMatch + fuzzyBonusConsecutive + fuzzyBonusBoundary)
+	if perfect == 0 {
+		return 1
+	}
+	return max(1, min(scoreSubstringHit-1, score*(scoreSubstringHit-1)/perfect))
+}
+
+// isTagField reports whether the field name selects the challenge tags
+func isTagField(field string) bool {
+	return field == "" || field == "tag" || field == "tags"
+}
+
+// challengeFieldValues returns the values of the named field of a challenge.
+// An empty field name selects the tags.
+func challengeFieldValues(c *ChallengeResult, field string) []string {
+	if isTagField(field) {
+		return c.Tags
+	}
+
+	switch field {
+	case "name":
+		return []string{c.Name}
+	case "desc", "description":
+		return []string{c.Description}
+	case "author":
+		return []string{c.Author}
+	case "flag":
+		return []string{c.Flag}
+	case "path":
+		return []string{c.FilePath}
+	case "branch":
+		return []string{c.BranchName}
+	}
+
+	value, ok := c.Extra[field]
+	if !ok {
+		return nil
+	}
+	return scalarStrings(value)
+}
+
+// scalarStrings flattens a decoded YAML value into strings. Lists yield one
+// string per scalar element; nested maps are ignored.
+func scalarStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			out = append(out, scalarStrings(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// Match reports whether the challenge satisfies the query
+func (q *Query) Match(c *ChallengeResult) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.match(c)
+}
+
+// Score rates how well a matching challenge satisfies the query
+func (q *Query) Score(c *ChallengeResult) int {
+	if q == nil || q.root == nil {
+		return 0
+	}
+	return q.root.score(c)
+}
+
+// Highlight returns the sorted rune positions of value matched by the query's
+// non-negated terms for the given field ("tag", "name", ...)
+func (q *Query) Highlight(field, value string) []int {
+	if q == nil {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	var positions []int
+	for _, term := range q.terms {
+		if term.field != field && !(isTagField(term.field) && isTagField(field)) {
+			continue
+		}
+		matched, ok := term.matches(value)
+		if !ok {
+			continue
+		}
+		for _, pos := range matched {
+			if !seen[pos] {
+				seen[pos] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+
+	sort.Ints(positions)
+	return positions
+}
+
+// Filter returns the challenges that satisfy the query with their Score set,
+// preserving their order
+func (q *Query) Filter(challenges []ChallengeResult) []ChallengeResult {
+	var results []ChallengeResult
+	for i := range challenges {
+		if q.Match(&challenges[i]) {
+			result := challenges[i]
+			result.Score = q.Score(&result)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+type tokenKind int
+
+const (
+	tokTerm tokenKind = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+// token is a lexical unit of a query
+type token struct {
+	kind  tokenKind
+	field string // Field qualifier of a term, e.g. "author" in author:"OSINT Team"
+	text  string // Term text; glob metacharacters from quoted sections are escaped
+	mode  MatchMode
+	moded bool // Mode was given explicitly (or implied by glob metacharacters)
+	pos   int  // rune offset in the input, used for error messages
+}
+
+// tokenizeQuery splits the input into tokens. Quoted sections are kept
+// together and never treated as operators. With rawTags set, tag terms are
+// read as regular expressions (see lexRegexTerm).
+func tokenizeQuery(input string, rawTags bool) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			// Leading dash excludes the following term or group
+			tokens = append(tokens, token{kind: tokNot, text: "-", pos: i})
+			i++
+		default:
+			lex := lexTerm
+			if rawTags {
+				lex = lexRegexTerm
+			}
+			tok, next, err := lex(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		}
+	}
+
+	return tokens, nil
+}
+
+// lexTerm reads a term (or an operator keyword) starting at runes[i] and
+// returns it together with the index just past it
+func lexTerm(runes []rune, i int) (token, int, error) {
+	tok := token{kind: tokTerm, pos: i}
+	var sb strings.Builder
+	quoted := false
+
+	for i < len(runes) {
+		r := runes[i]
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			break
+		}
+
+		if r == ':' && tok.field == "" && !quoted && !tok.moded && isFieldName(sb.String()) {
+			tok.field = strings.ToLower(sb.String())
+			sb.Reset()
+			i++
+			continue
+		}
+
+		// Mode markers are only recognized at the start of the value
+		if sb.Len() == 0 && !quoted && !tok.moded {
+			switch r {
+			case '=':
+				tok.mode, tok.moded = MatchExact, true
+				i++
+				continue
+			case '^':
+				tok.mode, tok.moded = MatchPrefix, true
+				i++
+				continue
+			case '~':
+				tok.mode, tok.moded = MatchSubstring, true
+				i++
+				continue
+			case '/':
+				end, pattern, err := lexRegex(runes, i)
+				if err != nil {
+					return tok, 0, err
+				}
+				if end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' {
+					return tok, 0, fmt.Errorf("unexpected %q after regex at position %d", runes[end], end+1)
+				}
+				tok.text, tok.mode, tok.moded = pattern, MatchRegex, true
+				return tok, end, nil
+			}
+		}
+
+		switch r {
+		case '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return tok, 0, fmt.Errorf("unterminated quote at position %d", i+1)
+			}
+			sb.WriteString(globEscape(string(runes[i+1 : end])))
+			quoted = true
+			i = end + 1
+			continue
+		case '*', '?':
+			if !tok.moded || tok.mode == MatchGlob {
+				tok.mode, tok.moded = MatchGlob, true
+			}
+		case '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+		i++
+	}
+
+	tok.text = sb.String()
+	if !quoted && tok.field == "" && !tok.moded {
+		tok.kind = keywordKind(tok.text)
+	}
+
+	return tok, i, nil
+}
+
+// keywordKind returns the operator token kind for AND, OR and NOT, and
+// tokTerm for any other text
+func keywordKind(text string) tokenKind {
+	switch text {
+	case "AND":
+		return tokAnd
+	case "OR":
+		return tokOr
+	case "NOT":
+		return tokNot
+	}
+	return tokTerm
+}
+
+// lexRegexTerm reads a term starting at runes[i] when the default match mode
+// is regex. Tag terms are taken verbatim as regular expressions, without mode
+// markers, glob metacharacters or escaping; a term may contain parentheses as
+// long as they are balanced within it. Other fields, and /slash-delimited/
+// values, are read by lexTerm as usual.
+func lexRegexTerm(runes []rune, i int) (token, int, error) {
+	tok := token{kind: tokTerm, pos: i}
+
+	start := i
+	for start < len(runes) && isFieldRune(runes[start]) {
+		start++
+	}
+	if start > i && start < len(runes) && runes[start] == ':' {
+		tok.field = strings.ToLower(string(runes[i:start]))
+		start++
+	} else {
+		start = i
+	}
+	if !isTagField(tok.field) || (start < len(runes) && runes[start] == '/') {
+		return lexTerm(runes, i)
+	}
+
+	var sb strings.Builder
+	quoted := false
+	depth := 0
+	for i = start; i < len(runes); i++ {
+		r := runes[i]
+		if unicode.IsSpace(r) || (r == ')' && depth == 0) {
+			break
+		}
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return tok, 0, fmt.Errorf("unterminated quote at position %d", i+1)
+			}
+			sb.WriteString(string(runes[i+1 : end]))
+			quoted = true
+			i = end
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	tok.text = sb.String()
+	if !quoted && tok.field == "" {
+		if kind := keywordKind(tok.text); kind != tokTerm {
+			tok.kind = kind
+			return tok, i, nil
+		}
+	}
+	tok.mode, tok.moded = MatchRegex, true
+	return tok, i, nil
+}
+
+// lexRegex reads a /slash-delimited/ regular expression starting at runes[i].
+// A backslash-escaped slash does not terminate the pattern.
+func lexRegex(runes []rune, i int) (int, string, error) {
+	var sb strings.Builder
+	for j := i + 1; j < len(runes); j++ {
+		switch {
+		case runes[j] == '\\' && j+1 < len(runes) && runes[j+1] == '/':
+			sb.WriteRune('/')
+			j++
+		case runes[j] == '/':
+			return j + 1, sb.String(), nil
+		default:
+			sb.WriteRune(runes[j])
+		}
+	}
+	return 0, "", fmt.Errorf("unterminated regex at position %d", i+1)
+}
+
+// isFieldName reports whether s can be used as a field qualifier
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !isFieldRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFieldRune reports whether r can appear in a field qualifier
+func isFieldRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+// queryParser is a recursive-descent parser over a token slice
+type queryParser struct {
+	tokens []token
+	pos    int
+	opts   QueryOptions
+}
+
+// ParseQuery parses a search expression with default options. See Query for the supported syntax.
+func ParseQuery(input string) (*Query, error) {
+	return ParseQueryWithOptions(input, QueryOptions{})
+}
+
+// ParseQueryWithOptions parses a search expression. See Query for the supported syntax.
+func ParseQueryWithOptions(input string, opts QueryOptions) (*Query, error) {
+	tokens, err := tokenizeQuery(input, opts.DefaultMode == MatchRegex)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return &Query{}, nil
+	}
+
+	p := &queryParser{tokens: tokens, opts: opts}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+	}
+
+	return &Query{root: root, terms: positiveTerms(root, false, nil)}, nil
+}
+
+// positiveTerms collects the terms of the tree that are not negated
+func positiveTerms(node queryNode, negated bool, out []*termNode) []*termNode {
+	switch n := node.(type) {
+	case *andNode:
+		for _, child := range n.children {
+			out = positiveTerms(child, negated, out)
+		}
+	case *orNode:
+		for _, child := range n.children {
+			out = positiveTerms(child, negated, out)
+		}
+	case *notNode:
+		out = positiveTerms(n.child, !negated, out)
+	case *synonymNode:
+		if !negated {
+			out = append(out, n.children...)
+		}
+	case *termNode:
+		if !negated {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (p *queryParser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+// parseOr parses: and ( OR and )*
+func (p *queryParser) parseOr() (queryNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []queryNode{first}
+	for tok := p.peek(); tok != nil && tok.kind == tokOr; tok = p.peek() {
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+// parseAnd parses: unary ( [AND] unary )*
+func (p *queryParser) parseAnd() (queryNode, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []queryNode{first}
+	for {
+		tok := p.peek()
+		if tok == nil || tok.kind == tokOr || tok.kind == tokRParen {
+			break
+		}
+		if tok.kind == tokAnd {
+			p.pos++
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &andNode{children: children}, nil
+}
+
+// parseUnary parses: NOT unary | primary
+func (p *queryParser) parseUnary() (queryNode, error) {
+	tok := p.peek()
+	if tok != nil && tok.kind == tokNot {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses: ( or ) | term
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+
+	switch tok.kind {
+	case tokLParen:
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing := p.peek()
+		if closing == nil || closing.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", tok.pos+1)
+		}
+		p.pos++
+		return node, nil
+	case tokTerm:
+		p.pos++
+		return p.newTermNode(tok)
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+	}
+}
+
+// newTermNode compiles the matcher for a term token. Tag terms are expanded
+// to their aliases and taxonomy descendants (see QueryOptions.expandTag);
+// glob and regex patterns are never expanded.
+func (p *queryParser) newTermNode(tok *token) (queryNode, error) {
+	mode := MatchSubstring
+	switch {
+	case tok.moded:
+		mode = tok.mode
+	case isTagField(tok.field):
+		mode = p.opts.DefaultMode
+	}
+
+	var synonyms []string
+	if isTagField(tok.field) && mode != MatchGlob && mode != MatchRegex {
+		synonyms = p.opts.expandTag(globUnescape(tok.text))
+	}
+	if len(synonyms) == 0 {
+		node, err := compileTermNode(tok, mode, tok.text)
+		if err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	node := &synonymNode{}
+	for _, synonym := range synonyms {
+		child, err := compileTermNode(tok, mode, globEscape(synonym))
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+	return node, nil
+}
+
+// compileTermNode builds a termNode for the field of tok with the given mode and text
+func compileTermNode(tok *token, mode MatchMode, text string) (*termNode, error) {
+	matches, err := compileMatcher(mode, text)
+	if err != nil {
+		return nil, fmt.Errorf("position %d: %w", tok.pos+1, err)
+	}
+
+	return &termNode{field: tok.field, text: text, mode: mode, matches: matches}, nil
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testChallenges() []ChallengeResult {
+	return []ChallengeResult{
+		{
+			Name:        "SQL Injection Basics",
+			Description: "Basic SQL injection vulnerability",
+			Author:      "Web Security Team",
+			Tags:        []string{"easy", "webandosint", "sql-injection", "beginner"},
+			FilePath:    "web/chall_3/challenge.yml",
+		},
+		{
+			Name:        "Geolocation Challenge",
+			Description: "Find the location from image metadata",
+			Author:      "OSINT Team",
+			Tags:        []string{"easy", "web", "osint", "geolocation"},
+			FilePath:    "osint/chall_2/challenge.yml",
+			Extra:       map[string]interface{}{"value": 500, "hints": []interface{}{"look at EXIF"}},
+		},
+		{
+			Name:        "Social Media Investigation",
+			Description: "Find information from social media posts",
+			Author:      "OSINT Team",
+			Tags:        []string{"medium", "osint", "social media", "beginner"},
+			FilePath:    "osint/chall_1/challenge.yml",
+		},
+	}
+}
+
+func resultNames(results []ChallengeResult) []string {
+	names := make([]string, 0, len(results))
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "Empty query matches everything",
+			query:    "",
+			expected: []string{"SQL Injection Basics", "Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Implicit AND",
+			query:    "easy osint",
+			expected: []string{"SQL Injection Basics", "Geolocation Challenge"},
+		},
+		{
+			name:     "Explicit AND with NOT",
+			query:    "easy AND osint AND NOT beginner",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "OR",
+			query:    "medium OR geolocation",
+			expected: []string{"Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Dash exclusion",
+			query:    "osint -easy",
+			expected: []string{"Social Media Investigation"},
+		},
+		{
+			name:     "Parentheses",
+			query:    "(medium OR sql) beginner",
+			expected: []string{"SQL Injection Basics", "Social Media Investigation"},
+		},
+		{
+			name:     "Excluded group",
+			query:    "-(medium OR sql)",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Quoted phrase",
+			query:    `"social media"`,
+			expected: []string{"Social Media Investigation"},
+		},
+		{
+			name:     "Quoted operator is a term",
+			query:    `"OR"`,
+			expected: []string{},
+		},
+		{
+			name:     "Quoted field value",
+			query:    `author:"OSINT Team"`,
+			expected: []string{"Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Field prefix match",
+			query:    "name:geo*",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Prefix does not match mid-value",
+			query:    "name:injection*",
+			expected: []string{},
+		},
+		{
+			name:     "Description field",
+			query:    "desc:metadata",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Field names are case-insensitive",
+			query:    "Author:web",
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Bare prefix term matches tags",
+			query:    "geo*",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Fields combine with operators",
+			query:    "author:osint -desc:image",
+			expected: []string{"Social Media Investigation"},
+		},
+		{
+			name:     "Path field",
+			query:    "path:web/",
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Extra scalar field",
+			query:    "value:500",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Extra list field",
+			query:    "hints:exif",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Unknown field matches nothing",
+			query:    "nosuchfield:x",
+			expected: []string{},
+		},
+		{
+			name:     "Dash inside a tag is not exclusion",
+			query:    "sql-injection",
+			expected: []string{"SQL Injection Basics"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.query, err)
+			}
+
+			got := resultNames(query.Filter(testChallenges()))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseQueryMatchModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		opts     QueryOptions
+		expected []string
+	}{
+		{
+			name:     "Default substring matches webandosint",
+			query:    "web",
+			expected: []string{"SQL Injection Basics", "Geolocation Challenge"},
+		},
+		{
+			name:     "Exact marker",
+			query:    "=web",
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Default exact mode",
+			query:    "web",
+			opts:     QueryOptions{DefaultMode: MatchExact},
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Substring marker overrides default mode",
+			query:    "~web",
+			opts:     QueryOptions{DefaultMode: MatchExact},
+			expected: []string{"SQL Injection Basics", "Geolocation Challenge"},
+		},
+		{
+			name:     "Default mode does not apply to other fields",
+			query:    "author:osint",
+			opts:     QueryOptions{DefaultMode: MatchExact},
+			expected: []string{"Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Prefix marker",
+			query:    "^web",
+			expected: []string{"SQL Injection Basics", "Geolocation Challenge"},
+		},
+		{
+			name:     "Glob in the middle",
+			query:    "sql-*n",
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Quoted star is literal",
+			query:    `"geo*"`,
+			expected: []string{},
+		},
+		{
+			name:     "Regex",
+			query:    "/^(web|medium)$/",
+			expected: []string{"Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Regex with spaces and field",
+			query:    "name:/media inv/ OR =easy",
+			expected: []string{"SQL Injection Basics", "Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Exact field value",
+			query:    `name:="Geolocation Challenge"`,
+			expected: []string{"Geolocation Challenge"},
+		},
+		{
+			name:     "Excluded exact term",
+			query:    "easy -=web",
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Default regex mode keeps stars as regex",
+			query:    "sql.*",
+			opts:     QueryOptions{DefaultMode: MatchRegex},
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Default regex mode keeps anchors and escapes",
+			query:    `^sql-\w+$`,
+			opts:     QueryOptions{DefaultMode: MatchRegex},
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Default regex mode keeps balanced parentheses in a term",
+			query:    "^(web|medium)$ OR =sql",
+			opts:     QueryOptions{DefaultMode: MatchRegex},
+			expected: []string{"Geolocation Challenge", "Social Media Investigation"},
+		},
+		{
+			name:     "Default regex mode with groups and operators",
+			query:    "(^geo|^sql) AND NOT ^web$",
+			opts:     QueryOptions{DefaultMode: MatchRegex},
+			expected: []string{"SQL Injection Basics"},
+		},
+		{
+			name:     "Default regex mode does not apply to other fields",
+			query:    "name:sql.*",
+			opts:     QueryOptions{DefaultMode: MatchRegex},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQueryWithOptions(tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.query, err)
+			}
+
+			got := resultNames(query.Filter(testChallenges()))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	invalid := []string{
+		"(easy OR web",
+		"easy)",
+		"easy AND",
+		"OR web",
+		`"unterminated`,
+		"NOT",
+		"/unterminated",
+		"/(/",
+		"/web/x",
+	}
+
+	for _, input := range invalid {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseQuery(input); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		})
+	}
+}
+
+func TestFilterChallengesByInput(t *testing.T) {
+	results, _, err := filterChallengesByInput(testChallenges(), "  ", QueryOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected blank input to return all challenges, got %d", len(results))
+	}
+
+	results, _, err = filterChallengesByInput(testChallenges(), "beginner -medium", QueryOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "SQL Injection Basics" {
+		t.Errorf("Expected only 'SQL Injection Basics', got %v", resultNames(results))
+	}
+
+	if _, _, err := filterChallengesByInput(testChallenges(), "(easy", QueryOptions{}); err == nil {
+		t.Error("Expected error for incomplete query")
+	}
+}
+
+func TestQueryHighlight(t *testing.T) {
+	query, err := ParseQuery("geo* -osint name:chall")
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	if got := query.Highlight("tag", "geolocation"); len(got) != len("geolocation") {
+		t.Errorf("Expected glob to highlight the whole tag, got %v", got)
+	}
+	if got := query.Highlight("tag", "osint"); len(got) != 0 {
+		t.Errorf("Expected negated term not to be highlighted, got %v", got)
+	}
+	if got := query.Highlight("name", "Geolocation Challenge"); !reflect.DeepEqual(got, []int{12, 13, 14, 15, 16}) {
+		t.Errorf("Expected name term to highlight 'Chall', got %v", got)
+	}
+}
+
+func TestParseQueryWithAliases(t *testing.T) {
+	opts := QueryOptions{Aliases: newAliasTable(map[string][]string{
+		"sqli": {"sql-injection"},
+		"geo":  {"geolocation"},
+		"sns":  {"social media"},
+	})}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"alias finds the tag it stands for", "sqli", []string{"SQL Injection Basics"}},
+		{"alias is case-insensitive", "SQLI", []string{"SQL Injection Basics"}},
+		{"alias with a space", "sns", []string{"Social Media Investigation"}},
+		{"alias keeps the explicit mode", "=geo", []string{"Geolocation Challenge"}},
+		{"negated alias", "easy -sqli", []string{"Geolocation Challenge"}},
+		{"field-qualified tag term", "tag:sqli", []string{"SQL Injection Basics"}},
+		{"other fields are not expanded", "name:sqli", []string{}},
+		{"globs are not expanded", "sqli*", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQueryWithOptions(tt.query, opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := resultNames(query.Filter(testChallenges()))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQueryAliasScoreAndHighlight(t *testing.T) {
+	opts := QueryOptions{Aliases: newAliasTable(map[string][]string{"sqli": {"sql-injection"}})}
+	query, err := ParseQueryWithOptions("sqli", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// An exact hit on a synonym scores like an exact hit on the term itself
+	challenge := testChallenges()[0]
+	if got := query.Score(&challenge); got != scoreExactHit {
+		t.Errorf("Expected score %d, got %d", scoreExactHit, got)
+	}
+	if got := query.Highlight("tag", "sql-injection"); len(got) != len("sql-injection") {
+		t.Errorf("Expected the whole synonym to be highlighted, got %v", got)
+	}
+}
+
+func TestParseQueryWithTaxonomy(t *testing.T) {
+	opts := QueryOptions{Taxonomy: testTaxonomy(t)}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"difficulty", []string{"SQL Injection Basics", "Geolocation Challenge", "Social Media Investigation"}},
+		{"web-exploitation", []string{"SQL Injection Basics"}},
+		{"recon -geolocation", []string{"Social Media Investigation"}},
+		{"category medium", []string{"Social Media Investigation"}},
+		{"name:recon", []string{}},
+	}
+
+	for _, tt := range tests {
+		query, err := ParseQueryWithOptions(tt.query, opts)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.query, err)
+		}
+		if got := resultNames(query.Filter(testChallenges())); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.expected, got)
+		}
+	}
+
+	// Aliases and the taxonomy combine: an alias of a parent tag expands to its children
+	opts.Aliases = newAliasTable(map[string][]string{"web-exploit": {"web-exploitation"}})
+	query, err := ParseQueryWithOptions("web-exploit", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := resultNames(query.Filter(testChallenges())); !reflect.DeepEqual(got, []string{"SQL Injection Basics"}) {
+		t.Errorf("Expected alias of a parent tag to expand, got %v", got)
+	}
+}
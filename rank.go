@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortKey selects the order in which search results are displayed
+type SortKey string
+
+const (
+	SortScore        SortKey = "score"         // highest relevance first
+	SortName         SortKey = "name"          // challenge name, A to Z
+	SortGenre        SortKey = "genre"         // genre, then relevance
+	SortPath         SortKey = "path"          // challenge.yml path
+	SortBranch       SortKey = "branch"        // branch name, then path
+	SortLastModified SortKey = "last-modified" // most recently modified first
+)
+
+// sortKeys lists the accepted sort keys in the order they are documented
+var sortKeys = []SortKey{SortScore, SortName, SortGenre, SortPath, SortBranch, SortLastModified}
+
+// parseSortKey converts a --sort value into a SortKey. An empty value sorts by score.
+func parseSortKey(name string) (SortKey, error) {
+	if name == "" {
+		return SortScore, nil
+	}
+	for _, key := range sortKeys {
+		if string(key) == strings.ToLower(name) {
+			return key, nil
+		}
+	}
+
+	names := make([]string, len(sortKeys))
+	for i, key := range sortKeys {
+		names[i] = string(key)
+	}
+	return SortScore, fmt.Errorf("unknown sort key %q (expected %s)", name, strings.Join(names, ", "))
+}
+
+// sortResults sorts the results in place. Ties keep their loading order, so
+// sorting by score with an empty query leaves the results unchanged.
+func sortResults(results []ChallengeResult, key SortKey) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		switch key {
+		case SortName:
+			if !strings.EqualFold(a.Name, b.Name) {
+				return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+			}
+			return a.FilePath < b.FilePath
+		case SortGenre:
+			if a.Genre != b.Genre {
+				return a.Genre < b.Genre
+			}
+			return a.Score > b.Score
+		case SortPath:
+			if a.FilePath != b.FilePath {
+				return a.FilePath < b.FilePath
+			}
+			return a.BranchName < b.BranchName
+		case SortBranch:
+			if a.BranchName != b.BranchName {
+				return a.BranchName < b.BranchName
+			}
+			return a.FilePath < b.FilePath
+		case SortLastModified:
+			return a.ModTime.After(b.ModTime)
+		default:
+			return a.Score > b.Score
+		}
+	})
+}
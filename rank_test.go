@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSortKey(t *testing.T) {
+	for _, key := range sortKeys {
+		parsed, err := parseSortKey(string(key))
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", key, err)
+		}
+		if parsed != key {
+			t.Errorf("Expected %q, got %q", key, parsed)
+		}
+	}
+
+	if key, err := parseSortKey(""); err != nil || key != SortScore {
+		t.Errorf("Expected empty sort key to default to score, got %q (%v)", key, err)
+	}
+
+	if _, err := parseSortKey("size"); err == nil {
+		t.Error("Expected error for unknown sort key")
+	}
+}
+
+func TestQueryScoreOrdering(t *testing.T) {
+	challenges := []ChallengeResult{
+		{Name: "Substring", Tags: []string{"osint-web"}},
+		{Name: "Prefix", Tags: []string{"web-security"}},
+		{Name: "Exact", Tags: []string{"web"}},
+		{Name: "Web Exact With Name", Tags: []string{"web"}},
+	}
+
+	query, err := ParseQuery("web")
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	results := query.Filter(challenges)
+	sortResults(results, SortScore)
+
+	expected := []string{"Web Exact With Name", "Exact", "Prefix", "Substring"}
+	got := resultNames(results)
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestQueryScorePatterns(t *testing.T) {
+	challenges := []ChallengeResult{
+		{Name: "Substring", Tags: []string{"osint-geo"}},
+		{Name: "Prefix", Tags: []string{"geolocation"}},
+		{Name: "Exact", Tags: []string{"geo"}},
+	}
+
+	// Glob and regex terms are ranked by what they matched, like plain terms
+	tests := []struct {
+		query    string
+		expected map[string]int
+	}{
+		{"geo*", map[string]int{"Prefix": scorePrefixHit, "Exact": scoreExactHit}},
+		{"*geo*", map[string]int{"Substring": scoreSubstringHit, "Prefix": scoreSubstringHit, "Exact": scoreExactHit}},
+		{"/geo/", map[string]int{"Substring": scoreSubstringHit, "Prefix": scorePrefixHit, "Exact": scoreExactHit}},
+		{"/^geo$/", map[string]int{"Exact": scoreExactHit}},
+	}
+
+	for _, tt := range tests {
+		query, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.query, err)
+		}
+		scores := make(map[string]int)
+		for _, result := range query.Filter(challenges) {
+			scores[result.Name] = result.Score
+		}
+		if !reflect.DeepEqual(scores, tt.expected) {
+			t.Errorf("%s: expected scores %v, got %v", tt.query, tt.expected, scores)
+		}
+	}
+}
+
+func TestQueryScoreCountsMatchedTerms(t *testing.T) {
+	challenges := []ChallengeResult{
+		{Name: "One", Tags: []string{"easy"}},
+		{Name: "Two", Tags: []string{"easy", "osint"}},
+	}
+
+	query, err := ParseQuery("easy OR osint")
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	results := query.Filter(challenges)
+	sortResults(results, SortScore)
+
+	if results[0].Name != "Two" {
+		t.Errorf("Expected challenge matching more terms first, got %v", resultNames(results))
+	}
+	if results[0].Score <= results[1].Score {
+		t.Errorf("Expected higher score for more matched terms, got %d and %d", results[0].Score, results[1].Score)
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	now := time.Now()
+	base := []ChallengeResult{
+		{Name: "beta", Genre: "web", FilePath: "web/b/challenge.yml", BranchName: "main", ModTime: now.Add(-time.Hour), Score: 10},
+		{Name: "Alpha", Genre: "osint", FilePath: "osint/a/challenge.yml", BranchName: "feat", ModTime: now, Score: 20},
+		{Name: "gamma", Genre: "osint", FilePath: "osint/c/challenge.yml", BranchName: "main", ModTime: now.Add(-2 * time.Hour), Score: 30},
+	}
+
+	tests := []struct {
+		key      SortKey
+		expected []string
+	}{
+		{key: SortScore, expected: []string{"gamma", "Alpha", "beta"}},
+		{key: SortName, expected: []string{"Alpha", "beta", "gamma"}},
+		{key: SortGenre, expected: []string{"gamma", "Alpha", "beta"}},
+		{key: SortPath, expected: []string{"Alpha", "gamma", "beta"}},
+		{key: SortBranch, expected: []string{"Alpha", "gamma", "beta"}},
+		{key: SortLastModified, expected: []string{"Alpha", "beta", "gamma"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			results := append([]ChallengeResult(nil), base...)
+			sortResults(results, tt.key)
+
+			got := resultNames(results)
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
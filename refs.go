@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RefKind is the kind of a git ref searched with --all-branches
+type RefKind string
+
+const (
+	RefLocal  RefKind = "local"  // Branches under refs/heads
+	RefRemote RefKind = "remote" // Remote-tracking branches under refs/remotes
+	RefTag    RefKind = "tag"    // Tags under refs/tags
+)
+
+// Ref is a branch or tag whose challenges can be loaded
+type Ref struct {
+	Name     string  // Short name shown in the results, e.g. main, origin/feat or v1.0
+	FullName string  // Full ref name, e.g. refs/remotes/origin/feat, used to read the ref unambiguously
+	Kind     RefKind // local, remote or tag
+	Line     string  // Branch name without the remote, so that origin/feat and feat share a line
+}
+
+// newRef creates a ref from its full name, returning false for refs that are
+// not searched, such as refs/remotes/origin/HEAD
+func newRef(fullName string) (Ref, bool) {
+	switch {
+	case strings.HasPrefix(fullName, "refs/heads/"):
+		name := strings.TrimPrefix(fullName, "refs/heads/")
+		return Ref{Name: name, FullName: fullName, Kind: RefLocal, Line: name}, true
+	case strings.HasPrefix(fullName, "refs/remotes/"):
+		name := strings.TrimPrefix(fullName, "refs/remotes/")
+		remote, branch, ok := strings.Cut(name, "/")
+		if !ok || remote == "" || branch == "HEAD" {
+			return Ref{}, false
+		}
+		return Ref{Name: name, FullName: fullName, Kind: RefRemote, Line: branch}, true
+	case strings.HasPrefix(fullName, "refs/tags/"):
+		name := strings.TrimPrefix(fullName, "refs/tags/")
+		return Ref{Name: name, FullName: fullName, Kind: RefTag, Line: name}, true
+	}
+	return Ref{}, false
+}
+
+// sameLine reports whether two refs are copies of the same branch, such as a
+// local branch and its remote-tracking branch. Tags are never on a line.
+func sameLine(a, b Ref) bool {
+	return a.Kind != RefTag && b.Kind != RefTag && a.Line == b.Line
+}
+
+// RefFilter selects the refs searched with --all-branches. The zero value
+// selects the local branches.
+type RefFilter struct {
+	Kinds   []RefKind // Kinds of refs searched; nil means local branches
+	Include []string  // Globs a ref must match, if any
+	Exclude []string  // Globs a ref must not match
+}
+
+// parseRefFilter parses the --refs and --exclude-refs flags. --refs is a
+// comma-separated list of kinds (local, remote, tags or all) and globs; globs
+// without a kind select among all refs.
+func parseRefFilter(refs, exclude string) (RefFilter, error) {
+	var filter RefFilter
+	for _, item := range splitList(refs) {
+		switch strings.ToLower(item) {
+		case "local":
+			filter.Kinds = append(filter.Kinds, RefLocal)
+		case "remote", "remotes":
+			filter.Kinds = append(filter.Kinds, RefRemote)
+		case "tag", "tags":
+			filter.Kinds = append(filter.Kinds, RefTag)
+		case "all":
+			filter.Kinds = append(filter.Kinds, RefLocal, RefRemote, RefTag)
+		default:
+			if _, err := path.Match(item, ""); err != nil {
+				return RefFilter{}, fmt.Errorf("invalid ref pattern %q: %w", item, err)
+			}
+			filter.Include = append(filter.Include, item)
+		}
+	}
+	for _, item := range splitList(exclude) {
+		if _, err := path.Match(item, ""); err != nil {
+			return RefFilter{}, fmt.Errorf("invalid ref pattern %q: %w", item, err)
+		}
+		filter.Exclude = append(filter.Exclude, item)
+	}
+
+	if filter.Kinds == nil && filter.Include != nil {
+		filter.Kinds = []RefKind{RefLocal, RefRemote, RefTag}
+	}
+	return filter, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// IsZero reports whether the filter selects the default, the local branches
+func (f RefFilter) IsZero() bool {
+	return f.Kinds == nil && f.Include == nil && f.Exclude == nil
+}
+
+// match reports whether the filter selects ref. Globs are matched against
+// both the ref name and its line, so "feat-*" also matches origin/feat-x.
+func (f RefFilter) match(ref Ref) bool {
+	kinds := f.Kinds
+	if kinds == nil {
+		kinds = []RefKind{RefLocal}
+	}
+	selected := false
+	for _, kind := range kinds {
+		selected = selected || kind == ref.Kind
+	}
+	if !selected {
+		return false
+	}
+
+	if f.Include != nil && !matchRefPatterns(f.Include, ref) {
+		return false
+	}
+	return !matchRefPatterns(f.Exclude, ref)
+}
+
+// matchRefPatterns reports whether ref matches any of the globs
+func matchRefPatterns(patterns []string, ref Ref) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, ref.Name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, ref.Line); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the refs selected by f, keeping their order
+func (f RefFilter) filter(refs []Ref) []Ref {
+	var selected []Ref
+	for _, ref := range refs {
+		if f.match(ref) {
+			selected = append(selected, ref)
+		}
+	}
+	return selected
+}
+
+// addRefFlags defines --refs and --exclude-refs on flags. The returned
+// function parses their values once the flags are parsed.
+func addRefFlags(flags *flag.FlagSet) func() (RefFilter, error) {
+	refs := flags.String("refs", "", "Refs searched: local (default), remote, tags, all and/or globs such as 'origin/chall-*', comma-separated")
+	exclude := flags.String("exclude-refs", "", "Comma-separated globs of refs not searched")
+	return func() (RefFilter, error) {
+		return parseRefFilter(*refs, *exclude)
+	}
+}
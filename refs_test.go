@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRef(t *testing.T) {
+	tests := []struct {
+		fullName string
+		expected Ref
+		ok       bool
+	}{
+		{"refs/heads/main", Ref{Name: "main", FullName: "refs/heads/main", Kind: RefLocal, Line: "main"}, true},
+		{"refs/heads/feat/x", Ref{Name: "feat/x", FullName: "refs/heads/feat/x", Kind: RefLocal, Line: "feat/x"}, true},
+		{"refs/remotes/origin/feat/x", Ref{Name: "origin/feat/x", FullName: "refs/remotes/origin/feat/x", Kind: RefRemote, Line: "feat/x"}, true},
+		{"refs/tags/v1.0", Ref{Name: "v1.0", FullName: "refs/tags/v1.0", Kind: RefTag, Line: "v1.0"}, true},
+		{"refs/remotes/origin/HEAD", Ref{}, false},
+		{"refs/stash", Ref{}, false},
+	}
+
+	for _, tt := range tests {
+		ref, ok := newRef(tt.fullName)
+		if ok != tt.ok || ref != tt.expected {
+			t.Errorf("newRef(%q) = %+v, %v, expected %+v, %v", tt.fullName, ref, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestSameLine(t *testing.T) {
+	local, _ := newRef("refs/heads/feat")
+	remote, _ := newRef("refs/remotes/origin/feat")
+	upstream, _ := newRef("refs/remotes/upstream/feat")
+	other, _ := newRef("refs/heads/other")
+	tag, _ := newRef("refs/tags/feat")
+
+	if !sameLine(local, remote) || !sameLine(remote, upstream) {
+		t.Error("Expected a branch and its remote-tracking branches to share a line")
+	}
+	if sameLine(local, other) || sameLine(local, tag) {
+		t.Error("Expected other branches and tags not to share the line")
+	}
+}
+
+func TestParseRefFilter(t *testing.T) {
+	tests := []struct {
+		refs, exclude string
+		expected      RefFilter
+		wantErr       bool
+	}{
+		{"", "", RefFilter{}, false},
+		{"local", "", RefFilter{Kinds: []RefKind{RefLocal}}, false},
+		{"remote, tags", "", RefFilter{Kinds: []RefKind{RefRemote, RefTag}}, false},
+		{"all", "", RefFilter{Kinds: []RefKind{RefLocal, RefRemote, RefTag}}, false},
+		{"origin/chall-*", "", RefFilter{Kinds: []RefKind{RefLocal, RefRemote, RefTag}, Include: []string{"origin/chall-*"}}, false},
+		{"remote,chall-*", "", RefFilter{Kinds: []RefKind{RefRemote}, Include: []string{"chall-*"}}, false},
+		{"", "wip-*,old", RefFilter{Exclude: []string{"wip-*", "old"}}, false},
+		{"[", "", RefFilter{}, true},
+		{"", "[", RefFilter{}, true},
+	}
+
+	for _, tt := range tests {
+		filter, err := parseRefFilter(tt.refs, tt.exclude)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRefFilter(%q, %q): expected an error", tt.refs, tt.exclude)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRefFilter(%q, %q): unexpected error: %v", tt.refs, tt.exclude, err)
+			continue
+		}
+		if !reflect.DeepEqual(filter, tt.expected) {
+			t.Errorf("parseRefFilter(%q, %q) = %+v, expected %+v", tt.refs, tt.exclude, filter, tt.expected)
+		}
+	}
+}
+
+func TestRefFilterMatch(t *testing.T) {
+	var refs []Ref
+	for _, fullName := range []string{
+		"refs/heads/main",
+		"refs/heads/chall-a",
+		"refs/heads/wip-b",
+		"refs/remotes/origin/main",
+		"refs/remotes/origin/chall-c",
+		"refs/remotes/origin/wip-b",
+		"refs/tags/v1",
+	} {
+		ref, _ := newRef(fullName)
+		refs = append(refs, ref)
+	}
+
+	tests := []struct {
+		refs, exclude string
+		expected      []string
+	}{
+		{"", "", []string{"main", "chall-a", "wip-b"}},
+		{"remote", "", []string{"origin/main", "origin/chall-c", "origin/wip-b"}},
+		{"tags", "", []string{"v1"}},
+		{"all", "wip-*", []string{"main", "chall-a", "origin/main", "origin/chall-c", "v1"}},
+		{"origin/chall-*", "", []string{"origin/chall-c"}},
+		// Globs also match the branch name without the remote
+		{"chall-*", "", []string{"chall-a", "origin/chall-c"}},
+		{"local,chall-*", "", []string{"chall-a"}},
+		{"", "main", []string{"chall-a", "wip-b"}},
+	}
+
+	for _, tt := range tests {
+		filter, err := parseRefFilter(tt.refs, tt.exclude)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var got []string
+		for _, ref := range filter.filter(refs) {
+			got = append(got, ref.Name)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("--refs %q --exclude-refs %q selected %v, expected %v", tt.refs, tt.exclude, got, tt.expected)
+		}
+	}
+}
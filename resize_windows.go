@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+)
+
+// notifyResize is a no-op on Windows, which has no SIGWINCH; the new size is
+// picked up on the next redraw
+func notifyResize(ch chan<- os.Signal) {}
+
+// stopResize is a no-op on Windows
+func stopResize(ch chan<- os.Signal) {}
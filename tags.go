@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// tagsSchemaVersion is the version of the JSON written by `searchall tags --format json`
+const tagsSchemaVersion = 1
+
+// TagStat counts how many challenges use a tag, in total and per genre and branch
+type TagStat struct {
+	Tag      string         `json:"tag"`
+	Count    int            `json:"count"`
+	Genres   map[string]int `json:"genres"`
+	Branches map[string]int `json:"branches,omitempty"` // Only with --all-branches
+}
+
+// collectTagStats aggregates the tags of all challenges. Tags are compared
+// case-insensitively and shown as first spelled; a tag listed twice in the
+// same challenge is counted once. Totals and genres are taken from the
+// deduplicated challenges, and the per-branch counts from copies, which holds
+// every branch's own copy of each challenge (nil without --all-branches).
+func collectTagStats(challenges, copies []ChallengeResult) []TagStat {
+	stats := make(map[string]*TagStat)
+	var order []string
+	// statsFor returns the distinct tags of a challenge, creating their stats
+	statsFor := func(challenge ChallengeResult) []*TagStat {
+		var result []*TagStat
+		seen := make(map[string]bool)
+		for _, tag := range challenge.Tags {
+			key := strings.ToLower(tag)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			stat, ok := stats[key]
+			if !ok {
+				stat = &TagStat{Tag: tag, Genres: make(map[string]int)}
+				stats[key] = stat
+				order = append(order, key)
+			}
+			result = append(result, stat)
+		}
+		return result
+	}
+
+	for _, challenge := range challenges {
+		for _, stat := range statsFor(challenge) {
+			stat.Count++
+			stat.Genres[challenge.Genre]++
+		}
+	}
+	for _, challenge := range copies {
+		for _, stat := range statsFor(challenge) {
+			if stat.Branches == nil {
+				stat.Branches = make(map[string]int)
+			}
+			stat.Branches[challenge.BranchName]++
+		}
+	}
+
+	result := make([]TagStat, 0, len(stats))
+	for _, key := range order {
+		result = append(result, *stats[key])
+	}
+	sortTagStats(result, "name")
+	return result
+}
+
+// sortTagStats orders tag statistics by "count" (most used first) or "name"
+func sortTagStats(stats []TagStat, key string) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if key == "count" && stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Tag < stats[j].Tag
+	})
+}
+
+// runTagsCommand implements `searchall tags`, which lists every tag with
+// the number of challenges using it
+func runTagsCommand(args []string) error {
+	flags := flag.NewFlagSet("tags", flag.ExitOnError)
+	allBranches := flags.Bool("all-branches", false, "Count tags across the refs selected by --refs and --exclude-refs (local branches by default)")
+	jobs := flags.Int("jobs", 0, "Branches and files loaded in parallel with --all-branches (0: one per CPU)")
+	sortFlag := flags.String("sort", "count", "Tag order: count or name")
+	formatFlag := flags.String("format", "table", "Output format: table or json")
+	refsFlags := addRefFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	refs, err := refsFlags()
+	if err != nil {
+		return err
+	}
+
+	if *sortFlag != "count" && *sortFlag != "name" {
+		return fmt.Errorf("unknown sort key %q (expected count or name)", *sortFlag)
+	}
+	if *formatFlag != "table" && *formatFlag != "json" {
+		return fmt.Errorf("unknown output format %q (expected table or json)", *formatFlag)
+	}
+
+	config, err := loadConfig("config.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config.yaml: %w", err)
+	}
+	loader, err := newChallengeLoader(*allBranches || !refs.IsZero(), *jobs, refs, config.Branches)
+	if err != nil {
+		return err
+	}
+	// Branches are counted from their own copies, not only the ones kept
+	if multi, ok := loader.(*MultiBranchLoader); ok {
+		multi.AllCopies = true
+	}
+	ctx, stop := newInterruptContext()
+	challenges, err := loader.LoadChallenges(ctx, config.Genre)
+	stop()
+	if err != nil {
+		return fmt.Errorf("failed to load challenges: %w", err)
+	}
+
+	var copies []ChallengeResult
+	if multi, ok := loader.(*MultiBranchLoader); ok {
+		copies = multi.Copies
+	}
+	stats := collectTagStats(challenges, copies)
+	sortTagStats(stats, *sortFlag)
+	inconsistent := findInconsistentAliases(stats, config.Aliases)
+
+	if *formatFlag == "json" {
+		return writeTagStatsJSON(os.Stdout, stats, inconsistent)
+	}
+	return writeTagStatsTable(os.Stdout, stats, inconsistent)
+}
+
+// writeTagStatsJSON writes the tag statistics as a versioned JSON document
+func writeTagStatsJSON(w io.Writer, stats []TagStat, inconsistent []AliasUsage) error {
+	if inconsistent == nil {
+		inconsistent = []AliasUsage{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Version             int          `json:"version"`
+		Tags                []TagStat    `json:"tags"`
+		InconsistentAliases []AliasUsage `json:"inconsistent_aliases"`
+	}{tagsSchemaVersion, stats, inconsistent})
+}
+
+// writeTagStatsTable writes the tag statistics as an aligned table, followed
+// by the alias groups that are used inconsistently. The BRANCHES column is
+// only shown when challenges were loaded from branches.
+func writeTagStatsTable(w io.Writer, stats []TagStat, inconsistent []AliasUsage) error {
+	showBranches := false
+	for _, stat := range stats {
+		if len(stat.Branches) > 0 {
+			showBranches = true
+			break
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if showBranches {
+		fmt.Fprintln(tw, "TAG\tCOUNT\tGENRES\tBRANCHES")
+	} else {
+		fmt.Fprintln(tw, "TAG\tCOUNT\tGENRES")
+	}
+	for _, stat := range stats {
+		if showBranches {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", stat.Tag, stat.Count, formatCounts(stat.Genres), formatCounts(stat.Branches))
+		} else {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", stat.Tag, stat.Count, formatCounts(stat.Genres))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(inconsistent) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Inconsistent aliases (several synonyms are used as tags):")
+		for _, usage := range inconsistent {
+			fmt.Fprintf(w, "  %s: %s\n", usage.Alias, formatCounts(usage.Tags))
+		}
+	}
+	return nil
+}
+
+// formatCounts renders per-key counts as "a:2 b:1", sorted by key
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s:%d", key, counts[key])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func tagTestChallenges() []ChallengeResult {
+	return []ChallengeResult{
+		{Name: "A", Genre: "web", Tags: []string{"easy", "sqli", "easy"}},
+		{Name: "B", Genre: "web", Tags: []string{"Easy", "xss"}},
+		{Name: "C", Genre: "osint", Tags: []string{"easy", "geo"}, BranchName: "feature/osint"},
+	}
+}
+
+func TestCollectTagStats(t *testing.T) {
+	challenges := tagTestChallenges()
+	// A lower-priority branch has its own copy of A with different tags
+	copies := []ChallengeResult{
+		{Name: "A", Genre: "web", Tags: []string{"easy", "sqli"}, BranchName: "main"},
+		{Name: "C", Genre: "osint", Tags: []string{"easy", "geo"}, BranchName: "feature/osint"},
+		{Name: "A", Genre: "web", Tags: []string{"EASY", "legacy"}, BranchName: "old"},
+	}
+	stats := collectTagStats(challenges, copies)
+
+	var tags []string
+	for _, stat := range stats {
+		tags = append(tags, stat.Tag)
+	}
+	if expected := []string{"easy", "geo", "legacy", "sqli", "xss"}; !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("Expected tags %v sorted by name, got %v", expected, tags)
+	}
+
+	easy := stats[0]
+	if easy.Count != 3 {
+		t.Errorf("Expected duplicate tags in one challenge, in any case, to count once, got %d", easy.Count)
+	}
+	if expected := map[string]int{"web": 2, "osint": 1}; !reflect.DeepEqual(easy.Genres, expected) {
+		t.Errorf("Expected genre counts %v, got %v", expected, easy.Genres)
+	}
+	if expected := map[string]int{"main": 1, "feature/osint": 1, "old": 1}; !reflect.DeepEqual(easy.Branches, expected) {
+		t.Errorf("Expected branch counts %v, got %v", expected, easy.Branches)
+	}
+
+	// A tag used only by a copy that was not kept is still counted for its branch
+	legacy := stats[2]
+	if legacy.Count != 0 || !reflect.DeepEqual(legacy.Branches, map[string]int{"old": 1}) {
+		t.Errorf("Expected legacy only on branch old, got %+v", legacy)
+	}
+
+	if stats := collectTagStats(challenges, nil); stats[0].Branches != nil {
+		t.Errorf("Expected no branch counts for working tree challenges, got %v", stats[0].Branches)
+	}
+}
+
+func TestSortTagStats(t *testing.T) {
+	stats := []TagStat{{Tag: "b", Count: 1}, {Tag: "c", Count: 2}, {Tag: "a", Count: 1}}
+
+	sortTagStats(stats, "count")
+	if stats[0].Tag != "c" || stats[1].Tag != "a" || stats[2].Tag != "b" {
+		t.Errorf("Expected count order c, a, b, got %v", stats)
+	}
+
+	sortTagStats(stats, "name")
+	if stats[0].Tag != "a" || stats[1].Tag != "b" || stats[2].Tag != "c" {
+		t.Errorf("Expected name order a, b, c, got %v", stats)
+	}
+}
+
+func TestWriteTagStatsTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTagStatsTable(&buf, collectTagStats(tagTestChallenges()[:2], nil), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "TAG   COUNT  GENRES\n" +
+		"easy  2      web:2\n" +
+		"sqli  1      web:1\n" +
+		"xss   1      web:1\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeTagStatsTable(&buf, collectTagStats(tagTestChallenges(), tagTestChallenges()[2:]), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasSuffix(lines[0], "BRANCHES") || !strings.Contains(lines[1], "osint:1 web:2  feature/osint:1") {
+		t.Errorf("Expected branch column, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteTagStatsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTagStatsJSON(&buf, collectTagStats(tagTestChallenges(), nil), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Version int       `json:"version"`
+		Tags    []TagStat `json:"tags"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Version != tagsSchemaVersion || len(doc.Tags) != 4 {
+		t.Fatalf("Expected version %d with 4 tags, got %+v", tagsSchemaVersion, doc)
+	}
+	if doc.Tags[0].Tag != "easy" || doc.Tags[0].Count != 3 {
+		t.Errorf("Expected 'easy' used 3 times first, got %+v", doc.Tags[0])
+	}
+	if strings.Contains(buf.String(), `"branches": null`) {
+		t.Error("Expected branches to be omitted when unknown")
+	}
+}
+
+func TestWriteTagStatsTableInconsistentAliases(t *testing.T) {
+	stats := []TagStat{{Tag: "geo", Count: 1, Genres: map[string]int{"osint": 1}}, {Tag: "geolocation", Count: 2, Genres: map[string]int{"osint": 2}}}
+	inconsistent := findInconsistentAliases(stats, map[string][]string{"geo": {"geolocation"}})
+
+	var buf bytes.Buffer
+	if err := writeTagStatsTable(&buf, stats, inconsistent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "\nInconsistent aliases (several synonyms are used as tags):\n  geo: geo:1 geolocation:2\n") {
+		t.Errorf("Expected inconsistent aliases after the table, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeTagStatsJSON(&buf, stats, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"inconsistent_aliases": []`) {
+		t.Errorf("Expected an empty inconsistent_aliases list, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Taxonomy is a hierarchy of tags, e.g. difficulty > easy, medium, hard. A
+// search for a parent tag also finds challenges tagged with any descendant,
+// and each top-level entry can be used as a --group-by facet.
+//
+// In YAML, each entry maps a tag to its children: a list of tags, a nested
+// mapping, or nothing for a leaf.
+//
+//	difficulty: [easy, medium, hard]
+//	technique:
+//	  web-exploitation: [sql-injection, xss]
+//	  osint: [geolocation]
+type Taxonomy struct {
+	roots    []string            // Top-level tags in definition order
+	children map[string][]string // Lower-cased tag to its direct children
+}
+
+// UnmarshalYAML builds the taxonomy from a YAML mapping
+func (t *Taxonomy) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: taxonomy must be a mapping of tags to their children", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		if !containsFold(t.roots, name) {
+			t.roots = append(t.roots, name)
+		}
+		if err := t.addChildren(name, node.Content[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addChildren records the children described by node under parent
+func (t *Taxonomy) addChildren(parent string, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag != "!!null" {
+			t.addChild(parent, node.Value)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				t.addChild(parent, item.Value)
+				continue
+			}
+			// A list item may itself be a mapping with a subtree
+			if err := t.addChildren(parent, item); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := node.Content[i].Value
+			t.addChild(parent, child)
+			if err := t.addChildren(child, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("line %d: unexpected value under %q in taxonomy", node.Line, parent)
+	}
+	return nil
+}
+
+// addChild links child under parent, ignoring duplicates
+func (t *Taxonomy) addChild(parent, child string) {
+	if t.children == nil {
+		t.children = make(map[string][]string)
+	}
+	key := strings.ToLower(parent)
+	if !containsFold(t.children[key], child) {
+		t.children[key] = append(t.children[key], child)
+	}
+}
+
+// merge adds the entries of other to the taxonomy
+func (t *Taxonomy) merge(other *Taxonomy) {
+	if other == nil {
+		return
+	}
+	for _, root := range other.roots {
+		if !containsFold(t.roots, root) {
+			t.roots = append(t.roots, root)
+		}
+	}
+	for parent, children := range other.children {
+		for _, child := range children {
+			t.addChild(parent, child)
+		}
+	}
+}
+
+// descendants returns every tag below tag in the hierarchy, depth first.
+// Cycles in the definition are ignored.
+func (t *Taxonomy) descendants(tag string) []string {
+	if t == nil {
+		return nil
+	}
+
+	var out []string
+	visited := map[string]bool{strings.ToLower(tag): true}
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, child := range t.children[strings.ToLower(parent)] {
+			if visited[strings.ToLower(child)] {
+				continue
+			}
+			visited[strings.ToLower(child)] = true
+			out = append(out, child)
+			walk(child)
+		}
+	}
+	walk(tag)
+	return out
+}
+
+// isFacet reports whether name is a top-level entry of the taxonomy
+func (t *Taxonomy) isFacet(name string) bool {
+	return t != nil && containsFold(t.roots, name)
+}
+
+// contains reports whether tag appears anywhere in the taxonomy
+func (t *Taxonomy) contains(tag string) bool {
+	if t == nil {
+		return false
+	}
+	if containsFold(t.roots, tag) {
+		return true
+	}
+	for _, children := range t.children {
+		if containsFold(children, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// facetValues returns the direct children of a facet
+func (t *Taxonomy) facetValues(facet string) []string {
+	if t == nil {
+		return nil
+	}
+	return t.children[strings.ToLower(facet)]
+}
+
+// loadTaxonomy combines the taxonomy file named in config.yaml, if any, with
+// the taxonomy section of config.yaml
+func loadTaxonomy(config *Config) (*Taxonomy, error) {
+	taxonomy := &Taxonomy{}
+	if config.TaxonomyFile != "" {
+		data, err := os.ReadFile(config.TaxonomyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read taxonomy file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, taxonomy); err != nil {
+			return nil, fmt.Errorf("failed to parse taxonomy file %s: %w", config.TaxonomyFile, err)
+		}
+	}
+	taxonomy.merge(config.Taxonomy)
+
+	if len(taxonomy.roots) == 0 {
+		return nil, nil
+	}
+	return taxonomy, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func testTaxonomy(t *testing.T) *Taxonomy {
+	t.Helper()
+	var taxonomy Taxonomy
+	content := `
+difficulty: [beginner, easy, medium, hard]
+category:
+  web-exploitation:
+    - sql-injection
+    - xss
+  recon:
+    - geolocation
+    - social:
+        - social media
+        - sns
+  misc:
+`
+	if err := yaml.Unmarshal([]byte(content), &taxonomy); err != nil {
+		t.Fatalf("Failed to parse taxonomy: %v", err)
+	}
+	return &taxonomy
+}
+
+func TestTaxonomyDescendants(t *testing.T) {
+	taxonomy := testTaxonomy(t)
+
+	tests := []struct {
+		tag      string
+		expected []string
+	}{
+		{"difficulty", []string{"beginner", "easy", "medium", "hard"}},
+		{"Web-Exploitation", []string{"sql-injection", "xss"}},
+		{"recon", []string{"geolocation", "social", "social media", "sns"}},
+		{"category", []string{"web-exploitation", "sql-injection", "xss", "recon", "geolocation", "social", "social media", "sns", "misc"}},
+		{"misc", nil},
+		{"easy", nil},
+	}
+
+	for _, tt := range tests {
+		if got := taxonomy.descendants(tt.tag); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("descendants(%q) = %v, want %v", tt.tag, got, tt.expected)
+		}
+	}
+
+	var none *Taxonomy
+	if got := none.descendants("difficulty"); got != nil {
+		t.Errorf("Expected nil taxonomy to have no descendants, got %v", got)
+	}
+}
+
+func TestTaxonomyCycle(t *testing.T) {
+	var taxonomy Taxonomy
+	if err := yaml.Unmarshal([]byte("a: [b]\nb: [a, c]\n"), &taxonomy); err != nil {
+		t.Fatalf("Failed to parse taxonomy: %v", err)
+	}
+	if got := taxonomy.descendants("a"); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("Expected cycle to be ignored, got %v", got)
+	}
+}
+
+func TestTaxonomyInvalid(t *testing.T) {
+	var taxonomy Taxonomy
+	if err := yaml.Unmarshal([]byte("- easy\n- hard\n"), &taxonomy); err == nil {
+		t.Error("Expected error for a taxonomy that is not a mapping")
+	}
+}
+
+func TestLoadTaxonomy(t *testing.T) {
+	taxonomyPath := filepath.Join(t.TempDir(), "taxonomy.yaml")
+	if err := os.WriteFile(taxonomyPath, []byte("platform: [linux, windows]\ndifficulty: [insane]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write taxonomy file: %v", err)
+	}
+
+	config := &Config{TaxonomyFile: taxonomyPath, Taxonomy: testTaxonomy(t)}
+	taxonomy, err := loadTaxonomy(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := []string{"platform", "difficulty", "category"}; !reflect.DeepEqual(taxonomy.roots, expected) {
+		t.Errorf("Expected roots %v, got %v", expected, taxonomy.roots)
+	}
+	if got, expected := taxonomy.facetValues("difficulty"), []string{"insane", "beginner", "easy", "medium", "hard"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the file and config.yaml entries to be merged, got %v", got)
+	}
+
+	if taxonomy, err := loadTaxonomy(&Config{}); taxonomy != nil || err != nil {
+		t.Errorf("Expected no taxonomy without configuration, got %v, %v", taxonomy, err)
+	}
+	if _, err := loadTaxonomy(&Config{TaxonomyFile: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("Expected error for a missing taxonomy file")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// resultTemplate renders search results with user-defined text/template
+// templates: the main template once per result, and the optional "header"
+// and "footer" templates before and after all results
+type resultTemplate struct {
+	tmpl *template.Template
+}
+
+// templateSummary is the data passed to the header and footer templates
+type templateSummary struct {
+	Results []ChallengeResult
+	Count   int
+}
+
+// templateFuncs are the helper functions available in templates, in
+// addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":      strings.Join,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"trim":      strings.TrimSpace,
+	"replace":   strings.ReplaceAll,
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"quote":     strconv.Quote,
+	"dir":       filepath.Dir,
+	"base":      filepath.Base,
+	"hasTag": func(tags []string, tag string) bool {
+		return slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, tag) })
+	},
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"truncate": func(length int, s string) string {
+		if utf8.RuneCountInString(s) <= length {
+			return s
+		}
+		return string([]rune(s)[:max(0, length-1)]) + "…"
+	},
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// parseResultTemplate parses the main template text. Header and footer
+// templates can be defined inside it with {{define "header"}} and
+// {{define "footer"}}; non-empty header or footer arguments replace them.
+func parseResultTemplate(text, header, footer string) (*resultTemplate, error) {
+	tmpl, err := template.New("result").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if header != "" {
+		if _, err := tmpl.New("header").Parse(header); err != nil {
+			return nil, fmt.Errorf("failed to parse header template: %w", err)
+		}
+	}
+	if footer != "" {
+		if _, err := tmpl.New("footer").Parse(footer); err != nil {
+			return nil, fmt.Errorf("failed to parse footer template: %w", err)
+		}
+	}
+	return &resultTemplate{tmpl: tmpl}, nil
+}
+
+// usesModTime reports whether any of the templates refers to .ModTime, which
+// is only looked up for the branch results when it is printed
+func (r *resultTemplate) usesModTime() bool {
+	for _, tmpl := range r.tmpl.Templates() {
+		if tmpl.Tree != nil && strings.Contains(tmpl.Tree.Root.String(), "ModTime") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadResultTemplate reads the main template from a file
+func loadResultTemplate(templatePath, header, footer string) (*resultTemplate, error) {
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+	return parseResultTemplate(string(data), header, footer)
+}
+
+// execute writes the header, every result and the footer to w. Each part
+// that does not end with a newline is followed by one, so that a one-line
+// template prints one line per result.
+func (r *resultTemplate) execute(w io.Writer, results []ChallengeResult) error {
+	summary := templateSummary{Results: results, Count: len(results)}
+
+	if err := r.render(w, "header", summary); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := r.render(w, "result", result); err != nil {
+			return err
+		}
+	}
+	return r.render(w, "footer", summary)
+}
+
+// render executes a single named template, if it is defined
+func (r *resultTemplate) render(w io.Writer, name string, data interface{}) error {
+	tmpl := r.tmpl.Lookup(name)
+	if tmpl == nil {
+		return nil
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return fmt.Errorf("failed to execute %s template: %w", name, err)
+	}
+	out := sb.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultTemplateExecute(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		header   string
+		footer   string
+		expected string
+	}{
+		{
+			name:     "one line per result",
+			text:     `[{{.Genre}}] {{.Name}} ({{join .Tags ", "}}) -> {{.FilePath}}`,
+			expected: "[osint] Geolocation Challenge (osint, geolocation) -> osint/chall_2/challenge.yml\n[web] Bare Challenge () -> web/chall_1/challenge.yml\n",
+		},
+		{
+			name:     "header and footer flags",
+			text:     `- {{.Name}}`,
+			header:   `# {{.Count}} challenges`,
+			footer:   `({{len .Results}} total)`,
+			expected: "# 2 challenges\n- Geolocation Challenge\n- Bare Challenge\n(2 total)\n",
+		},
+		{
+			name:     "header and footer defined in the template",
+			text:     `{{define "header"}}<ul>{{end}}{{define "footer"}}</ul>{{end -}} <li>{{.Name}}</li>`,
+			expected: "<ul>\n<li>Geolocation Challenge</li>\n<li>Bare Challenge</li>\n</ul>\n",
+		},
+		{
+			name:     "flags replace defined header",
+			text:     `{{define "header"}}old{{end -}}{{.Name}}`,
+			header:   `new`,
+			expected: "new\nGeolocation Challenge\nBare Challenge\n",
+		},
+		{
+			name:     "empty output skips the result",
+			text:     `{{if hasTag .Tags "OSINT"}}{{.Name}}{{end}}`,
+			expected: "Geolocation Challenge\n",
+		},
+		{
+			name:     "helper functions",
+			text:     `{{upper .Genre}} {{dir .FilePath}} {{base .FilePath}} {{default "-" .BranchName}} {{truncate 5 .Name}} {{date "2006-01-02" .ModTime}} {{json .Tags}}`,
+			expected: "OSINT osint/chall_2 challenge.yml feature/osint Geol… 2024-05-01 [\"osint\",\"geolocation\"]\nWEB web/chall_1 challenge.yml - Bare…  null\n",
+		},
+		{
+			name:     "extra fields",
+			text:     `{{.Name}}: {{index .Extra "value"}}`,
+			expected: "Geolocation Challenge: 100\nBare Challenge: <no value>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := parseResultTemplate(tt.text, tt.header, tt.footer)
+		if err != nil {
+			t.Errorf("%s: unexpected parse error: %v", tt.name, err)
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.execute(&buf, outputTestResults()); err != nil {
+			t.Errorf("%s: unexpected execute error: %v", tt.name, err)
+			continue
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, buf.String())
+		}
+	}
+}
+
+func TestResultTemplateErrors(t *testing.T) {
+	if _, err := parseResultTemplate("{{.Name", "", ""); err == nil {
+		t.Error("Expected parse error for unclosed action")
+	}
+	if _, err := parseResultTemplate("{{.Name}}", "{{if}}", ""); err == nil {
+		t.Error("Expected parse error for invalid header")
+	}
+
+	tmpl, err := parseResultTemplate("{{.NoSuchField}}", "", "")
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+	if err := tmpl.execute(&bytes.Buffer{}, outputTestResults()); err == nil {
+		t.Error("Expected execute error for unknown field")
+	}
+}
+
+func TestResultTemplateUsesModTime(t *testing.T) {
+	tests := []struct {
+		text     string
+		footer   string
+		expected bool
+	}{
+		{`{{.Name}}`, "", false},
+		{`{{date "2006-01-02" .ModTime}} {{.Name}}`, "", true},
+		{`{{.Name}}`, `{{range .Results}}{{.ModTime}}{{end}}`, true},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := parseResultTemplate(tt.text, "", tt.footer)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.text, err)
+		}
+		if got := tmpl.usesModTime(); got != tt.expected {
+			t.Errorf("%q, %q: expected %v, got %v", tt.text, tt.footer, tt.expected, got)
+		}
+	}
+}
+
+func TestLoadResultTemplate(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "wiki.tmpl")
+	content := "{{define \"header\"}}| Name | Path |\n| --- | --- |{{end -}}\n| {{.Name}} | {{.FilePath}} |\n"
+	if err := os.WriteFile(templatePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	tmpl, err := loadResultTemplate(templatePath, "", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	results := []ChallengeResult{{Name: "Geolocation Challenge", FilePath: "osint/chall_2/challenge.yml", ModTime: time.Now()}}
+	if err := tmpl.execute(&buf, results); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "| Name | Path |\n| --- | --- |\n| Geolocation Challenge | osint/chall_2/challenge.yml |\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	if _, err := loadResultTemplate(filepath.Join(t.TempDir(), "missing.tmpl"), "", ""); err == nil {
+		t.Error("Expected error for missing template file")
+	}
+}
+
+func TestSelectResultTemplate(t *testing.T) {
+	if tmpl, err := selectResultTemplate("", "", "", ""); tmpl != nil || err != nil {
+		t.Errorf("Expected no template without flags, got %v, %v", tmpl, err)
+	}
+	if _, err := selectResultTemplate("{{.Name}}", "wiki.tmpl", "", ""); err == nil {
+		t.Error("Expected error for --template with --template-file")
+	}
+	if _, err := selectResultTemplate("", "", "# header", ""); err == nil {
+		t.Error("Expected error for --template-header without a template")
+	}
+	if tmpl, err := selectResultTemplate("{{.Name}}", "", "", ""); tmpl == nil || err != nil {
+		t.Errorf("Expected template, got %v, %v", tmpl, err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+// controllingTerminal is the device the interactive UI is drawn on when
+// stdout is redirected, e.g. by `cd "$(searchall)"`
+const controllingTerminal = "/dev/tty"
+
+// controllingTerminalInput is the device keys are read from. It is opened
+// separately from stdin so that closing it stops the key reader before an
+// editor takes over the terminal.
+const controllingTerminalInput = "/dev/tty"
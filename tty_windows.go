@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+// controllingTerminal is the console output device, used when stdout is redirected
+const controllingTerminal = "CONOUT$"
+
+// controllingTerminalInput is the console input device keys are read from
+const controllingTerminalInput = "CONIN$"
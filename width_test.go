@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r        rune
+		expected int
+	}{
+		{r: 'a', expected: 1},
+		{r: 'é', expected: 1},
+		{r: 'ア', expected: 2},
+		{r: 'ｱ', expected: 1}, // Halfwidth katakana
+		{r: '位', expected: 2},
+		{r: '한', expected: 2},
+		{r: 'Ａ', expected: 2}, // Fullwidth latin
+		{r: '🔍', expected: 2},
+		{r: '́', expected: 0}, // Combining acute accent
+		{r: '​', expected: 0}, // Zero width space
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.r), func(t *testing.T) {
+			if got := runeWidth(tt.r); got != tt.expected {
+				t.Errorf("Expected width %d for %U, got %d", tt.expected, tt.r, got)
+			}
+		})
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	if got := stringWidth("位置 geo"); got != 8 {
+		t.Errorf("Expected width 8, got %d", got)
+	}
+	if got := stringWidth(""); got != 0 {
+		t.Errorf("Expected width 0, got %d", got)
+	}
+}